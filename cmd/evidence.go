@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"ctx/internal/agent"
@@ -9,7 +10,13 @@ import (
 )
 
 func init() {
+	evidenceAddCmd.Flags().String("note", "", "Note recorded in the evidence index and shown next to the path in prompts")
+	evidenceAddCmd.Flags().String("name", "", "File name for evidence read from stdin")
+	evidenceAddCmd.Flags().String("glob", "", "Only ingest directory files whose name or relative path matches this glob")
+	evidenceAddCmd.Flags().String("type", "", "Parse as a test report: go-test-json, junit or tap (default: auto-detect)")
+	evidenceScanCmd.Flags().Bool("redact", false, "Rewrite files with findings in place, redacting the secrets")
 	evidenceCmd.AddCommand(evidenceAddCmd)
+	evidenceCmd.AddCommand(evidenceScanCmd)
 	rootCmd.AddCommand(evidenceCmd)
 }
 
@@ -19,9 +26,13 @@ var evidenceCmd = &cobra.Command{
 }
 
 var evidenceAddCmd = &cobra.Command{
-	Use:   "add <file>",
+	Use:   "add <file|dir|->",
 	Short: "Copy evidence into .agent/evidence/ and link it to the active work item",
-	Args:  cobra.ExactArgs(1),
+	Long: `Copy a file into .agent/evidence/ and link it to the active work item.
+
+Use - to read stdin (requires --name). A directory is ingested recursively into
+.agent/evidence/<WI>/<dir>/, keeping its structure; --glob filters the files.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := agent.EnsureAgentExists(); err != nil {
 			return err
@@ -35,25 +46,128 @@ var evidenceAddCmd = &cobra.Command{
 		}
 
 		src := args[0]
-		if _, err := os.Stat(src); err != nil {
-			return fmt.Errorf("source evidence %q not found: %w", src, err)
+		note, _ := cmd.Flags().GetString("note")
+		name, _ := cmd.Flags().GetString("name")
+		glob, _ := cmd.Flags().GetString("glob")
+		reportType, _ := cmd.Flags().GetString("type")
+		if err := agent.ValidateTestReportFormat(reportType); err != nil {
+			return err
 		}
 
-		destRel, err := agent.CopyEvidence(src)
-		if err != nil {
-			return err
+		var stored []agent.StoredEvidence
+		switch {
+		case src == "-":
+			if name == "" {
+				return fmt.Errorf("reading evidence from stdin requires --name")
+			}
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			s, err := agent.WriteEvidence(name, data, agent.EvidenceEntry{Source: "stdin", Note: note})
+			if err != nil {
+				return err
+			}
+			stored = append(stored, s)
+		default:
+			info, err := os.Stat(src)
+			if err != nil {
+				return fmt.Errorf("source evidence %q not found: %w", src, err)
+			}
+			if name != "" {
+				return fmt.Errorf("--name only applies when reading stdin (-)")
+			}
+			if info.IsDir() {
+				if stored, err = agent.CopyEvidenceDir(state.ActiveWorkItem, src, glob, note); err != nil {
+					return err
+				}
+				if len(stored) == 0 {
+					return fmt.Errorf("no files in %s match %q", src, glob)
+				}
+				break
+			}
+			if glob != "" {
+				return fmt.Errorf("--glob only applies to directories")
+			}
+			s, err := agent.CopyEvidence(src, note)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, s)
 		}
 
-		wi, err := agent.LoadWorkItem(state.ActiveWorkItem)
-		if err != nil {
+		for _, s := range stored {
+			if err := agent.AttachEvidence(state.ActiveWorkItem, s.Path); err != nil {
+				return err
+			}
+			if reportType != "" {
+				if s.Tests, err = agent.SetTestReport(s.Path, reportType); err != nil {
+					return err
+				}
+			}
+			if s.Deduped {
+				fmt.Printf("Identical content already stored; linked %s to %s.\n", s.Path, state.ActiveWorkItem)
+			} else {
+				fmt.Printf("Added evidence %s to %s.\n", s.Path, state.ActiveWorkItem)
+			}
+			printStoreNotes(s)
+		}
+		return nil
+	},
+}
+
+var evidenceScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Audit stored evidence for secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
 			return err
 		}
-		wi.Meta.Evidence = append(wi.Meta.Evidence, destRel)
-		if err := agent.SaveWorkItem(wi); err != nil {
+		redact, _ := cmd.Flags().GetBool("redact")
+		results, err := agent.ScanEvidence(redact)
+		if err != nil {
 			return err
 		}
-
-		fmt.Printf("Added evidence %s to %s.\n", destRel, wi.Meta.ID)
-		return nil
+		if len(results) == 0 {
+			fmt.Println("No secrets found in evidence.")
+			return nil
+		}
+		total := 0
+		for _, r := range results {
+			fmt.Println(agent.FormatFindings(r.Path, r.Findings))
+			total += len(r.Findings)
+		}
+		if redact {
+			fmt.Printf("Redacted %d secret(s) in %d file(s).\n", total, len(results))
+			return nil
+		}
+		return fmt.Errorf("%d secret(s) found in %d evidence file(s); rerun with --redact to scrub them", total, len(results))
 	},
 }
+
+// printStoreNotes reports redaction, compression and quota warnings for stored evidence.
+func printStoreNotes(stored agent.StoredEvidence) {
+	if stored.Redacted {
+		fmt.Printf("Redacted %d secret(s):\n%s\n", len(stored.Findings), agent.FormatFindings(stored.Path, stored.Findings))
+		if stored.Quarantined != "" {
+			fmt.Printf("Original quarantined outside the repo at %s\n", stored.Quarantined)
+		}
+	}
+	if stored.Compressed {
+		fmt.Printf("Compressed %s per evidence.compress_over_bytes.\n", stored.Path)
+	}
+	if t := stored.Tests; t != nil {
+		fmt.Printf("Test report (%s): %d test(s), %d passed, %d failed, %d skipped.\n", t.Format, t.Total, t.Passed, t.Failed, t.Skipped)
+		for _, f := range t.Failures {
+			fmt.Printf("  FAIL %s\n", f)
+		}
+	}
+	if len(stored.Frames) > 0 {
+		top := stored.Frames[0]
+		fmt.Printf("Found %d stack frame(s) in repo files (top: %s:%d).\n", len(stored.Frames), top.File, top.Line)
+	}
+	for _, w := range stored.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+}
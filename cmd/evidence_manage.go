@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	evidenceListCmd.Flags().String("id", "", "Work item to list (default: the active item)")
+	evidenceListCmd.Flags().Bool("all", false, "List every stored or linked evidence file")
+	evidenceRmCmd.Flags().String("id", "", "Work item to unlink from (default: the active item)")
+	evidenceShowCmd.Flags().Bool("no-pager", false, "Print directly instead of using $PAGER")
+	evidenceGCCmd.Flags().Bool("dry-run", false, "Report what would be deleted without deleting it")
+	evidencePruneCmd.Flags().Bool("dry-run", false, "Report what would change without changing it")
+	evidenceCmd.AddCommand(evidenceListCmd)
+	evidenceCmd.AddCommand(evidenceRmCmd)
+	evidenceCmd.AddCommand(evidenceShowCmd)
+	evidenceCmd.AddCommand(evidenceGCCmd)
+	evidenceCmd.AddCommand(evidencePruneCmd)
+}
+
+var evidenceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List evidence for a work item",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		all, _ := cmd.Flags().GetBool("all")
+		id := ""
+		if !all {
+			var err error
+			if id, err = workItemFlag(cmd); err != nil {
+				return err
+			}
+		}
+		infos, err := agent.ListEvidence(id, all)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			if all {
+				fmt.Println("No evidence stored.")
+			} else {
+				fmt.Printf("No evidence linked to %s.\n", id)
+			}
+			return nil
+		}
+		for _, info := range infos {
+			size := fmt.Sprintf("%d B", info.Size)
+			if info.Missing {
+				size = "missing"
+			}
+			typ := info.Type
+			if typ == "" {
+				typ = "-"
+			}
+			line := fmt.Sprintf("%-48s %10s  %s", info.Path, size, typ)
+			if all {
+				items := strings.Join(info.WorkItems, ",")
+				if items == "" {
+					items = "unreferenced"
+				}
+				line += "  " + items
+			}
+			if info.Note != "" {
+				line += "  — " + info.Note
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var evidenceRmCmd = &cobra.Command{
+	Use:   "rm <path>",
+	Short: "Unlink evidence from a work item (run ctx evidence gc to delete unreferenced files)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		id, err := workItemFlag(cmd)
+		if err != nil {
+			return err
+		}
+		rel := agent.NormalizeEvidencePath(args[0])
+		if err := agent.DetachEvidence(id, rel); err != nil {
+			return err
+		}
+		fmt.Printf("Unlinked %s from %s.\n", rel, id)
+		return nil
+	},
+}
+
+var evidenceShowCmd = &cobra.Command{
+	Use:   "show <path>",
+	Short: "Print an evidence file, paging on a terminal",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		rel := agent.NormalizeEvidencePath(args[0])
+		data, err := agent.ReadEvidence(rel)
+		if err != nil {
+			return err
+		}
+		if agent.IsBinary(data) {
+			fmt.Printf("%s is binary (%d bytes); open .agent/%s directly.\n", rel, len(data), rel)
+			return nil
+		}
+		noPager, _ := cmd.Flags().GetBool("no-pager")
+		return page(data, noPager)
+	},
+}
+
+var evidenceGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete evidence files no work item links and prune the index",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		result, err := agent.GCEvidence(dryRun)
+		if err != nil {
+			return err
+		}
+		verb := "Removed"
+		if dryRun {
+			verb = "Would remove"
+		}
+		for _, rel := range result.Removed {
+			fmt.Printf("%s %s\n", verb, rel)
+		}
+		fmt.Printf("%s %d file(s), %d bytes; pruned %d index entries.\n", verb, len(result.Removed), result.Bytes, result.Pruned)
+		return nil
+	},
+}
+
+var evidencePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Apply the evidence policy in .agent/policy.yaml: expire, compress, and check quotas",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		result, err := agent.PruneEvidence(dryRun)
+		if err != nil {
+			return err
+		}
+		expire, compress := "Expired", "Compressed"
+		if dryRun {
+			expire, compress = "Would expire", "Would compress"
+		}
+		for _, rel := range result.Expired {
+			fmt.Printf("%s %s\n", expire, rel)
+		}
+		for _, rel := range result.Compressed {
+			fmt.Printf("%s %s\n", compress, rel)
+		}
+		for _, w := range result.Warnings {
+			fmt.Printf("warning: %s\n", w)
+		}
+		fmt.Printf("%s %d and %s %d file(s); %d bytes saved.\n", expire, len(result.Expired), strings.ToLower(compress), len(result.Compressed), result.Saved)
+		return nil
+	},
+}
+
+// workItemFlag returns --id or the active work item.
+func workItemFlag(cmd *cobra.Command) (string, error) {
+	id, _ := cmd.Flags().GetString("id")
+	if id != "" {
+		return id, nil
+	}
+	state, err := agent.LoadState()
+	if err != nil {
+		return "", err
+	}
+	if state.ActiveWorkItem == "" {
+		return "", fmt.Errorf("no active work item; pass --id or start one with ctx work start <WI-XXX>")
+	}
+	return state.ActiveWorkItem, nil
+}
+
+// page writes data through $PAGER (default less) when stdout is a terminal.
+func page(data []byte, noPager bool) error {
+	stat, err := os.Stdout.Stat()
+	if noPager || err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-FRX"}
+	}
+	if _, err := exec.LookPath(pager[0]); err != nil {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	c := exec.Command(pager[0], pager[1:]...)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
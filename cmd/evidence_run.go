@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	evidenceRunCmd.Flags().SetInterspersed(false)
+	evidenceRunCmd.Flags().String("dir", ".", "Working directory for the command, relative to the repo root")
+	evidenceRunCmd.Flags().String("note", "", "Note recorded in the evidence index and shown next to the path in prompts")
+	evidenceRunCmd.Flags().Bool("health", false, "Set health status to ok or failing from the exit code")
+	evidenceCmd.AddCommand(evidenceRunCmd)
+}
+
+var evidenceRunCmd = &cobra.Command{
+	Use:   "run [flags] -- <command> [args...]",
+	Short: "Run a command, tee its output, and store it as evidence on the active work item",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		state, err := agent.LoadState()
+		if err != nil {
+			return err
+		}
+		if state.ActiveWorkItem == "" {
+			return fmt.Errorf("no active work item; start one with ctx work start <WI-XXX>")
+		}
+		dir, _ := cmd.Flags().GetString("dir")
+		note, _ := cmd.Flags().GetString("note")
+		setHealth, _ := cmd.Flags().GetBool("health")
+
+		result, err := agent.RunEvidence(state.ActiveWorkItem, args, dir, note, os.Stdout)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved output of `%s` (exit %d, %s) as %s on %s.\n", result.Run.Command, result.Run.ExitCode, result.Run.Duration, result.Stored.Path, state.ActiveWorkItem)
+		printStoreNotes(result.Stored)
+
+		if setHealth {
+			state.Health.Status = "ok"
+			if result.Run.ExitCode != 0 {
+				state.Health.Status = "failing"
+			}
+			if err := agent.SaveState(state); err != nil {
+				return err
+			}
+			fmt.Printf("Health: %s\n", state.Health.Status)
+		}
+		if result.Run.ExitCode != 0 {
+			return fmt.Errorf("command exited with status %d", result.Run.ExitCode)
+		}
+		return nil
+	},
+}
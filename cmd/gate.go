@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	gateCmd.AddCommand(gateRunCmd)
+	rootCmd.AddCommand(gateCmd)
+}
+
+var gateCmd = &cobra.Command{
+	Use:   "gate",
+	Short: "Run executable quality gates",
+}
+
+var gateRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Run executable quality gates and record results, evidence, and health",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		runs, err := agent.RunGates(name, os.Stdout)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		fmt.Println()
+		for _, run := range runs {
+			status := "PASS"
+			if !run.Result.Passed {
+				status = "FAIL"
+				failed++
+			}
+			fmt.Printf("%s  %-24s exit %d, %s, output %s\n", status, run.Gate.Key(), run.Result.ExitCode, run.Result.Duration, run.Result.Evidence)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d quality gate(s) failed", failed, len(runs))
+		}
+		fmt.Printf("All %d quality gate(s) passed.\n", len(runs))
+		return nil
+	},
+}
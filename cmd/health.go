@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	healthShowCmd.Flags().Bool("all", false, "Include resolved issues")
+	healthIssueAddCmd.Flags().String("severity", agent.SeverityMedium, "Severity: low, medium, high, or critical")
+	healthIssueAddCmd.Flags().String("source", "", "Where the issue was observed, e.g. a dashboard or alert name")
+	healthIssueAddCmd.Flags().StringSlice("work-item", nil, "Link the issue to work items (repeatable)")
+	healthIssueCmd.AddCommand(healthIssueAddCmd)
+	healthIssueCmd.AddCommand(healthIssueResolveCmd)
+	healthCmd.AddCommand(healthSetCmd)
+	healthCmd.AddCommand(healthShowCmd)
+	healthCmd.AddCommand(healthIssueCmd)
+	rootCmd.AddCommand(healthCmd)
+}
+
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Manage project health shown in prompts",
+}
+
+var healthSetCmd = &cobra.Command{
+	Use:   "set <status>",
+	Short: "Set the health status (for example ok, degraded, failing)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		state, err := agent.LoadState()
+		if err != nil {
+			return err
+		}
+		state.Health.Status = args[0]
+		if err := agent.SaveState(state); err != nil {
+			return err
+		}
+		fmt.Printf("Health: %s\n", state.Health.Status)
+		return nil
+	},
+}
+
+var healthShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show health status and open issues",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		state, err := agent.LoadState()
+		if err != nil {
+			return err
+		}
+		all, _ := cmd.Flags().GetBool("all")
+		fmt.Printf("Health: %s\n", state.Health.Status)
+		issues := state.Health.OpenIssues("")
+		if all {
+			issues = state.Health.Issues
+		}
+		if len(issues) == 0 {
+			fmt.Println("No open issues.")
+			return nil
+		}
+		for _, issue := range issues {
+			line := fmt.Sprintf("%-5s %s", issue.ID, issue)
+			if !issue.Open() {
+				line += fmt.Sprintf(" [resolved %s]", issue.ResolvedAt.Format("2006-01-02"))
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var healthIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Track health issues",
+}
+
+var healthIssueAddCmd = &cobra.Command{
+	Use:   "add \"<summary>\"",
+	Short: "Open a health issue",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		severity, _ := cmd.Flags().GetString("severity")
+		source, _ := cmd.Flags().GetString("source")
+		workItems, _ := cmd.Flags().GetStringSlice("work-item")
+		for _, id := range workItems {
+			if _, err := agent.LoadWorkItem(id); err != nil {
+				return fmt.Errorf("could not load %s: %w", id, err)
+			}
+		}
+		state, err := agent.LoadState()
+		if err != nil {
+			return err
+		}
+		issue, err := state.Health.AddHealthIssue(args[0], severity, source, workItems)
+		if err != nil {
+			return err
+		}
+		if err := agent.SaveState(state); err != nil {
+			return err
+		}
+		fmt.Printf("Opened %s %s\n", issue.ID, issue)
+		return nil
+	},
+}
+
+var healthIssueResolveCmd = &cobra.Command{
+	Use:   "resolve <id>",
+	Short: "Mark a health issue resolved",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		state, err := agent.LoadState()
+		if err != nil {
+			return err
+		}
+		issue, err := state.Health.ResolveHealthIssue(args[0])
+		if err != nil {
+			return err
+		}
+		if err := agent.SaveState(state); err != nil {
+			return err
+		}
+		fmt.Printf("Resolved %s: %s\n", issue.ID, issue.Summary)
+		return nil
+	},
+}
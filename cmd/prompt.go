@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"ctx/internal/agent"
 	"github.com/spf13/cobra"
@@ -9,6 +12,12 @@ import (
 
 func init() {
 	promptCmd.Flags().StringP("profile", "p", "cheap", "Prompt profile to use (cheap|standard|deep)")
+	promptCmd.Flags().String("format", "", "Output format (md|xml|json|txt|chat-json); defaults to the profile's format or md")
+	promptCmd.Flags().String("model", "", "Model name for chat-json request bodies (defaults to the profile's model)")
+	promptCmd.Flags().String("send", "", "POST the chat-json body to a localhost chat completions URL and save the response as evidence")
+	promptCmd.Flags().String("out", "", "Write the prompt to this path instead of .agent/exports/")
+	promptCmd.Flags().Bool("stdout", false, "Write the prompt to stdout only; status messages go to stderr")
+	promptCmd.Flags().Bool("stats", false, "Print estimated token counts per section")
 	rootCmd.AddCommand(promptCmd)
 }
 
@@ -20,11 +29,68 @@ var promptCmd = &cobra.Command{
 			return err
 		}
 		profile, _ := cmd.Flags().GetString("profile")
-		dest, err := agent.BuildPrompt(profile)
+		format, _ := cmd.Flags().GetString("format")
+		model, _ := cmd.Flags().GetString("model")
+		sendURL, _ := cmd.Flags().GetString("send")
+		out, _ := cmd.Flags().GetString("out")
+		toStdout, _ := cmd.Flags().GetBool("stdout")
+		stats, _ := cmd.Flags().GetBool("stats")
+		if toStdout && out != "" {
+			return fmt.Errorf("--stdout and --out cannot be combined")
+		}
+		if sendURL != "" {
+			if format != "" && format != agent.FormatChatJSON {
+				return fmt.Errorf("--send requires --format %s", agent.FormatChatJSON)
+			}
+			format = agent.FormatChatJSON
+		}
+		result, err := agent.BuildPrompt(agent.PromptOptions{
+			Profile: profile,
+			Format:  format,
+			Model:   model,
+			Out:     out,
+			Stdout:  toStdout,
+		})
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Prompt written to %s\n", dest)
+
+		// Keep stdout clean for piping when the prompt itself goes there.
+		status := io.Writer(os.Stdout)
+		if toStdout {
+			status = os.Stderr
+			fmt.Print(result.Output)
+		} else {
+			fmt.Fprintf(status, "Prompt written to %s\n", result.Path)
+		}
+		if len(result.Trimmed) > 0 {
+			fmt.Fprintf(status, "Trimmed to fit %d tokens: %s\n", result.MaxTokens, strings.Join(result.Trimmed, ", "))
+		}
+		if result.MaxTokens > 0 && result.Tokens > result.MaxTokens {
+			fmt.Fprintf(status, "Warning: prompt is still ~%d tokens, over the %d token budget.\n", result.Tokens, result.MaxTokens)
+		}
+		if stats {
+			printPromptStats(status, result)
+		}
+		if sendURL != "" {
+			sent, err := agent.SendChatPrompt(sendURL, result.WorkItem, []byte(result.Output))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(status, "Sent to %s (HTTP %d); response saved as evidence %s.\n", sendURL, sent.StatusCode, sent.Evidence)
+		}
 		return nil
 	},
 }
+
+func printPromptStats(w io.Writer, result agent.PromptResult) {
+	fmt.Fprintf(w, "Section tokens (%s):\n", result.Tokenizer)
+	for _, s := range result.Sections {
+		fmt.Fprintf(w, "  %-16s %6d\n", s.Name, s.Tokens)
+	}
+	if result.MaxTokens > 0 {
+		fmt.Fprintf(w, "  %-16s %6d / %d\n", "total", result.Tokens, result.MaxTokens)
+	} else {
+		fmt.Fprintf(w, "  %-16s %6d\n", "total", result.Tokens)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	promptDiffCmd.Flags().String("id", "", "Work item whose history to diff (default: active work item)")
+	promptCmd.AddCommand(promptDiffCmd)
+}
+
+var promptDiffCmd = &cobra.Command{
+	Use:   "diff [a] [b]",
+	Short: "Show what changed between two prompts in a work item's history",
+	Long:  "Compare prompts kept under .agent/exports/history/<WI>/. With no arguments the two latest prompts are compared; with one, it is compared to the latest. Arguments are history paths or entry names such as 20260101T120000Z-cheap.",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		id, _ := cmd.Flags().GetString("id")
+		if id == "" {
+			state, err := agent.LoadState()
+			if err != nil {
+				return err
+			}
+			if state.ActiveWorkItem == "" {
+				return fmt.Errorf("no active work item; pass --id <WI-XXX>")
+			}
+			id = state.ActiveWorkItem
+		}
+		diff, err := agent.DiffPrompts(id, args...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("From: %s (%s, %s)\n", diff.From.File, diff.From.Profile, diff.From.CreatedAt.Format("2006-01-02 15:04:05Z"))
+		fmt.Printf("To:   %s (%s, %s)\n", diff.To.File, diff.To.Profile, diff.To.CreatedAt.Format("2006-01-02 15:04:05Z"))
+		if len(diff.SourceChanges) == 0 {
+			fmt.Println("Sources: unchanged")
+		} else {
+			fmt.Println("Sources:")
+			for _, c := range diff.SourceChanges {
+				fmt.Printf("- %s\n", c)
+			}
+		}
+		if diff.Unified == "" {
+			fmt.Println("Prompt text: identical")
+			return nil
+		}
+		fmt.Print(diff.Unified)
+		return nil
+	},
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	promptValidateCmd.Flags().StringP("profile", "p", "", "Validate only this profile (default: all profiles)")
+	promptCmd.AddCommand(promptValidateCmd)
+}
+
+var promptValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Parse and dry-render prompt templates against the active work item",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		profile, _ := cmd.Flags().GetString("profile")
+		problems, err := agent.ValidatePromptTemplates(profile)
+		if err != nil {
+			return err
+		}
+		errs := 0
+		for _, p := range problems {
+			level := "error"
+			if p.Warning {
+				level = "warning"
+			} else {
+				errs++
+			}
+			fmt.Printf("%s [%s]: %s\n", level, p.Profile, p.Message)
+		}
+		if errs > 0 {
+			return fmt.Errorf("%d prompt template error(s)", errs)
+		}
+		fmt.Println("Prompt templates OK.")
+		return nil
+	},
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	templateSaveCmd.Flags().Bool("generic", false, "Drop the project name and summary so the template fits other repos")
+	templateSaveCmd.Flags().Bool("force", false, "Overwrite existing template file")
+	templateExportCmd.Flags().Bool("resolved", false, "Merge the templates it extends so the file stands alone")
+	templateExportCmd.Flags().Bool("force", false, "Overwrite existing file")
+	templateImportCmd.Flags().String("name", "", "Template name (default: the file name without extension)")
+	templateImportCmd.Flags().Bool("force", false, "Overwrite existing template file")
+	templateCmd.AddCommand(templateSaveCmd)
+	templateCmd.AddCommand(templateExportCmd)
+	templateCmd.AddCommand(templateImportCmd)
+}
+
+var templateSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save .agent/context.yaml as a repo template",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		generic, _ := cmd.Flags().GetBool("generic")
+		force, _ := cmd.Flags().GetBool("force")
+		dest, err := agent.SaveTemplate(args[0], generic, force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved .agent/context.yaml as template %q at %s\n", args[0], dest)
+		return nil
+	},
+}
+
+var templateExportCmd = &cobra.Command{
+	Use:   "export <name> <file>",
+	Short: "Write a template to a file for use in another repo",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resolved, _ := cmd.Flags().GetBool("resolved")
+		force, _ := cmd.Flags().GetBool("force")
+		if err := agent.ExportTemplate(args[0], args[1], resolved, force); err != nil {
+			return err
+		}
+		fmt.Printf("Exported template %q to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+var templateImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Copy a template file into .agent/templates",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		name, _ := cmd.Flags().GetString("name")
+		force, _ := cmd.Flags().GetBool("force")
+		dest, warnings, err := agent.ImportTemplate(args[0], name, force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s to %s\n", args[0], dest)
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	templateShowCmd.Flags().Bool("resolved", false, "Merge the templates it extends (or a base+overlay composition) and show the result")
+	templateCmd.AddCommand(templateShowCmd)
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a template as YAML",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resolved, _ := cmd.Flags().GetBool("resolved")
+		data, err := agent.TemplateYAML(args[0], resolved)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"ctx/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	tokensCmd.Flags().String("tokenizer", "", "Tokenizer to use (chars4|approx|cl100k_base|<name> from .agent/tokenizers)")
+	tokensCmd.Flags().StringP("profile", "p", "", "Use the tokenizer configured for this prompt profile")
+	rootCmd.AddCommand(tokensCmd)
+}
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens <file>...",
+	Short: "Count tokens in files, including evidence paths such as evidence/test.log",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("tokenizer")
+		profileName, _ := cmd.Flags().GetString("profile")
+		if name == "" && profileName != "" {
+			if err := agent.EnsureAgentExists(); err != nil {
+				return err
+			}
+			profiles, err := agent.LoadPromptProfiles()
+			if err != nil {
+				return err
+			}
+			profile, err := agent.ResolvePromptProfile(profiles, profileName)
+			if err != nil {
+				return err
+			}
+			name = profile.Tokenizer
+		}
+		tok, err := agent.LoadTokenizer(name)
+		if err != nil {
+			return err
+		}
+
+		total := 0
+		for _, arg := range args {
+			path := arg
+			read := os.ReadFile
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				// Evidence is recorded relative to .agent/ and may be stored gzipped.
+				if _, err := os.Stat(agent.AgentPath(arg)); err == nil {
+					path = agent.AgentPath(arg)
+					read = func(string) ([]byte, error) { return agent.ReadEvidence(arg) }
+				}
+			}
+			data, err := read(path)
+			if err != nil {
+				return err
+			}
+			n := tok.Count(string(data))
+			total += n
+			fmt.Printf("%8d  %s\n", n, path)
+		}
+		if len(args) > 1 {
+			fmt.Printf("%8d  total\n", total)
+		}
+		fmt.Printf("Tokenizer: %s\n", tok.Name())
+		return nil
+	},
+}
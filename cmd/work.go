@@ -13,6 +13,10 @@ import (
 func init() {
 	workCmd.AddCommand(workStartCmd)
 	workCmd.AddCommand(workStopCmd)
+	workDoneCmd.Flags().String("summary", "", "Handoff summary (default: the item's last summary)")
+	workDoneCmd.Flags().Bool("force", false, "Complete even if definition-of-done checks fail (requires --reason)")
+	workDoneCmd.Flags().String("reason", "", "Why the item is being forced done; recorded on the work item")
+	workCmd.AddCommand(workDoneCmd)
 	rootCmd.AddCommand(workCmd)
 }
 
@@ -100,3 +104,50 @@ var workStopCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var workDoneCmd = &cobra.Command{
+	Use:   "done [WI-XXX]",
+	Short: "Complete a work item after checking the definition of done",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := agent.EnsureAgentExists(); err != nil {
+			return err
+		}
+		id := ""
+		if len(args) == 1 {
+			id = args[0]
+		} else {
+			state, err := agent.LoadState()
+			if err != nil {
+				return err
+			}
+			if state.ActiveWorkItem == "" {
+				return fmt.Errorf("no active work item; pass a work item ID")
+			}
+			id = state.ActiveWorkItem
+		}
+		summary, _ := cmd.Flags().GetString("summary")
+		force, _ := cmd.Flags().GetBool("force")
+		reason, _ := cmd.Flags().GetString("reason")
+
+		result, err := agent.CompleteWorkItem(id, strings.TrimSpace(summary), force, reason)
+		if len(result.Missing) > 0 {
+			fmt.Println("Definition of done:")
+			for _, m := range result.Missing {
+				fmt.Printf("- %s\n", m)
+			}
+		}
+		if err != nil {
+			if len(result.Missing) > 0 {
+				return fmt.Errorf("%w; fix the above or use --force --reason \"...\"", err)
+			}
+			return err
+		}
+		if result.Forced {
+			fmt.Printf("%s forced done; reason and %d failing check(s) recorded.\n", id, len(result.Missing))
+			return nil
+		}
+		fmt.Printf("%s is done.\n", id)
+		return nil
+	},
+}
@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table; larger inputs are shown as a whole replacement.
+const maxDiffCells = 4_000_000
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes a line diff using an LCS table after trimming the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		ops = append(ops, lcsDiff(midA, midB)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func lcsDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff renders a unified diff with the given number of context lines; it is empty when
+// the inputs are identical.
+func unifiedDiff(nameA, nameB, a, b string, context int) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(ops); {
+		// Find the next change and grow the hunk while changes are within 2*context lines.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*context {
+				break
+			}
+		}
+		lo := max(first-context, start)
+		hi := min(end+context, len(ops))
+
+		lineA, lineB := 1, 1
+		for _, op := range ops[:lo] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, op := range ops[lo:hi] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		// An empty range names the line before it, as in diff -u.
+		if countA == 0 {
+			lineA--
+		}
+		if countB == 0 {
+			lineB--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, op := range ops[lo:hi] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		start = hi
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
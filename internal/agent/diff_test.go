@@ -0,0 +1,87 @@
+package agent
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		name, a, b string
+		context    int
+		want       string
+	}{
+		{
+			name: "identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+		},
+		{
+			name:    "pure insert",
+			a:       "a\nb\nc\n",
+			b:       "a\nb\nnew\nc\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -2,2 +2,3 @@\n b\n+new\n c\n",
+		},
+		{
+			name:    "insert into empty",
+			a:       "",
+			b:       "x\ny\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name:    "pure delete",
+			a:       "a\nb\nc\nd\n",
+			b:       "a\nd\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,4 +1,2 @@\n a\n-b\n-c\n d\n",
+		},
+		{
+			name:    "insert without context",
+			a:       "a\nb\n",
+			b:       "a\nnew\nb\n",
+			context: 0,
+			want:    "--- a\n+++ b\n@@ -1,0 +2,1 @@\n+new\n",
+		},
+		{
+			name:    "replace",
+			a:       "a\nb\nc\n",
+			b:       "a\nB\nc\n",
+			context: 0,
+			want:    "--- a\n+++ b\n@@ -2,1 +2,1 @@\n-b\n+B\n",
+		},
+		{
+			// Changes 2*context lines apart share one hunk.
+			name:    "merged hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n",
+			b:       "1\nX\n3\n4\nY\n6\n7\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,6 +1,6 @@\n 1\n-2\n+X\n 3\n 4\n-5\n+Y\n 6\n",
+		},
+		{
+			// Further apart, they get separate hunks with their own line numbers.
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:       "1\nX\n3\n4\n5\n6\nY\n8\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n@@ -6,3 +6,3 @@\n 6\n-7\n+Y\n 8\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", tc.a, tc.b, tc.context); got != tc.want {
+				t.Errorf("unifiedDiff:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesKeepsCommonLines(t *testing.T) {
+	a := []string{"x", "a", "b", "c", "y"}
+	b := []string{"x", "b", "c", "d", "y"}
+	var kinds string
+	for _, op := range diffLines(a, b) {
+		kinds += string(op.kind)
+	}
+	if kinds != " -  + " {
+		t.Errorf("ops = %q, want %q", kinds, " -  + ")
+	}
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DonePolicy is the definition of done checked by ctx work done. Unset checks are enabled.
+type DonePolicy struct {
+	// RequireAcceptanceChecked fails on open acceptance criteria in the body or front matter, or
+	// when there are none at all.
+	RequireAcceptanceChecked *bool `yaml:"require_acceptance_checked,omitempty"`
+	// RequireGatesPassed needs every executable gate to have passed on the current working tree.
+	RequireGatesPassed *bool `yaml:"require_gates_passed,omitempty"`
+	// RequireEvidenceForIntents needs evidence on items with these intents (default: bugfix).
+	RequireEvidenceForIntents []string `yaml:"require_evidence_for_intents,omitempty"`
+	RequireHandoffSummary     *bool    `yaml:"require_handoff_summary,omitempty"`
+}
+
+// DoneOverride records a completion forced past failing checks.
+type DoneOverride struct {
+	Reason  string    `yaml:"reason"`
+	Missing []string  `yaml:"missing"`
+	At      time.Time `yaml:"at"`
+}
+
+// DoneResult reports the outcome of CompleteWorkItem.
+type DoneResult struct {
+	ID      string
+	Missing []string
+	Forced  bool
+}
+
+var (
+	uncheckedItemPattern = regexp.MustCompile(`(?m)^\s*[-*+] \[ \]\s*(.*)$`)
+	checkboxItemPattern  = regexp.MustCompile(`(?m)^\s*[-*+] \[[ xX]\]`)
+	checkedPrefix        = regexp.MustCompile(`^\[[xX]\]\s*`)
+	uncheckedPrefix      = regexp.MustCompile(`^\[ \]\s*`)
+)
+
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+func (p DonePolicy) evidenceIntents() []string {
+	if p.RequireEvidenceForIntents == nil {
+		return []string{"bugfix"}
+	}
+	return p.RequireEvidenceForIntents
+}
+
+// uncheckedAcceptance reports open criteria from the body's Acceptance Criteria checklist and the
+// acceptance_criteria front matter, where only items starting with "[x]" count as met.
+func uncheckedAcceptance(wi *WorkItemFile) []string {
+	var missing []string
+	section, _ := selectMarkdownSections(wi.Body, []string{"Acceptance Criteria"})
+	for _, m := range uncheckedItemPattern.FindAllStringSubmatch(section, -1) {
+		missing = append(missing, "acceptance criterion not checked: "+strings.TrimSpace(m[1]))
+	}
+	for _, c := range wi.Meta.AcceptanceCriteria {
+		c = strings.TrimSpace(c)
+		if !checkedPrefix.MatchString(c) {
+			missing = append(missing, "acceptance criterion not checked: "+uncheckedPrefix.ReplaceAllString(c, ""))
+		}
+	}
+	if len(wi.Meta.AcceptanceCriteria) == 0 && !checkboxItemPattern.MatchString(section) {
+		missing = append(missing, "no acceptance criteria found (add - [ ] items under ## Acceptance Criteria or acceptance_criteria front matter)")
+	}
+	return missing
+}
+
+// checkDone lists the definition-of-done checks a work item fails.
+func checkDone(wi *WorkItemFile, summary string, state State, context Context, policy DonePolicy) ([]string, error) {
+	var missing []string
+	if enabled(policy.RequireAcceptanceChecked) {
+		missing = append(missing, uncheckedAcceptance(wi)...)
+	}
+
+	if enabled(policy.RequireGatesPassed) {
+		var fingerprint string
+		for _, g := range context.QualityGates {
+			if !g.Executable() {
+				continue
+			}
+			if fingerprint == "" {
+				var err error
+				if fingerprint, err = repoFingerprint(); err != nil {
+					return nil, err
+				}
+			}
+			r, ok := state.Gates[g.Key()]
+			switch {
+			case !ok:
+				missing = append(missing, fmt.Sprintf("quality gate %s has not run (ctx gate run %s)", g.Key(), g.Key()))
+			case !r.Passed:
+				missing = append(missing, fmt.Sprintf("quality gate %s is failing: %s", g.Key(), r.Status()))
+			case r.Fingerprint != fingerprint:
+				missing = append(missing, fmt.Sprintf("quality gate %s passed on an older working tree; rerun ctx gate run %s", g.Key(), g.Key()))
+			}
+		}
+	}
+
+	for _, intent := range policy.evidenceIntents() {
+		if containsString(wi.Meta.Intent, intent) && len(wi.Meta.Evidence) == 0 {
+			missing = append(missing, fmt.Sprintf("no evidence attached for %s work (ctx evidence add)", intent))
+			break
+		}
+	}
+
+	if enabled(policy.RequireHandoffSummary) && strings.TrimSpace(summary) == "" {
+		missing = append(missing, "handoff summary is empty (pass --summary)")
+	}
+	return missing, nil
+}
+
+// CompleteWorkItem checks the done policy and marks the work item done. Failing checks return an
+// error unless force is set, in which case the reason and the failing checks are recorded.
+func CompleteWorkItem(id, summary string, force bool, reason string) (DoneResult, error) {
+	result := DoneResult{ID: id}
+	if force && strings.TrimSpace(reason) == "" {
+		return result, fmt.Errorf("--force requires --reason")
+	}
+	wi, err := LoadWorkItem(id)
+	if err != nil {
+		return result, err
+	}
+	if wi.Meta.Status == "done" {
+		return result, fmt.Errorf("%s is already done", id)
+	}
+	state, err := LoadState()
+	if err != nil {
+		return result, err
+	}
+	context, err := LoadContext()
+	if err != nil {
+		return result, err
+	}
+	policy, err := LoadPolicy()
+	if err != nil {
+		return result, err
+	}
+	if summary == "" {
+		summary = wi.Meta.LastSummary
+	}
+
+	result.Missing, err = checkDone(wi, summary, state, context, policy.Done)
+	if err != nil {
+		return result, err
+	}
+	if len(result.Missing) > 0 && !force {
+		return result, fmt.Errorf("%s is not done: %d check(s) failing", id, len(result.Missing))
+	}
+
+	now := time.Now().UTC()
+	wi.Meta.Status = "done"
+	wi.Meta.DoneAt = &now
+	wi.Meta.LastSummary = summary
+	if len(result.Missing) > 0 {
+		result.Forced = true
+		wi.Meta.DoneOverride = &DoneOverride{Reason: strings.TrimSpace(reason), Missing: result.Missing, At: now}
+	}
+	if err := SaveWorkItem(wi); err != nil {
+		return result, err
+	}
+
+	if state.ActiveWorkItem == id {
+		state.ActiveWorkItem = ""
+		state.BranchSuggestion = ""
+	}
+	if summary != "" {
+		state.LastSummary = summary
+	}
+	return result, SaveState(state)
+}
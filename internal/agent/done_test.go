@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func gatesOnlyPolicy() DonePolicy {
+	off := false
+	return DonePolicy{
+		RequireAcceptanceChecked:  &off,
+		RequireEvidenceForIntents: []string{},
+		RequireHandoffSummary:     &off,
+	}
+}
+
+func gateMissing(t *testing.T) []string {
+	t.Helper()
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := LoadContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := checkDone(NewWorkItemFile("WI-001", "x", nil), "", state, ctx, gatesOnlyPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return missing
+}
+
+func TestCheckDoneGateFingerprint(t *testing.T) {
+	for _, useGit := range []bool{true, false} {
+		name := "plain"
+		if useGit {
+			name = "git"
+		}
+		t.Run(name, func(t *testing.T) {
+			if useGit {
+				if _, err := exec.LookPath("git"); err != nil {
+					t.Skip("git not installed")
+				}
+			}
+			inTempRepo(t)
+			if err := os.WriteFile("main.go", []byte("package main\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if useGit {
+				for _, args := range [][]string{
+					{"init", "-q"},
+					{"add", "main.go"},
+					{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+				} {
+					if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+						t.Fatalf("git %v: %v\n%s", args, err, out)
+					}
+				}
+			}
+			// The second gate writes a file; that must not invalidate either gate.
+			setTestGates(t, QualityGate{Name: "ok", Command: "true"}, QualityGate{Name: "gen", Command: "touch generated.txt"})
+			for i := 0; i < 2; i++ {
+				if _, err := RunGates("", io.Discard); err != nil {
+					t.Fatal(err)
+				}
+				if missing := gateMissing(t); len(missing) != 0 {
+					t.Fatalf("run %d: missing = %q, want none", i+1, missing)
+				}
+			}
+
+			if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			missing := gateMissing(t)
+			if len(missing) != 2 || !strings.Contains(missing[0], "older working tree") {
+				t.Errorf("after an edit, missing = %q, want both gates stale", missing)
+			}
+		})
+	}
+}
+
+func TestCheckDoneAcceptanceAndSummary(t *testing.T) {
+	wi := NewWorkItemFile("WI-001", "Fix crash", []string{"bugfix"})
+	wi.Body = "## Acceptance Criteria\n- [x] no crash\n- [ ] regression test\n\n## Notes\n- [ ] not a criterion\n"
+	missing, err := checkDone(wi, "", State{}, Context{}, DonePolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"acceptance criterion not checked: regression test",
+		"no evidence attached for bugfix work (ctx evidence add)",
+		"handoff summary is empty (pass --summary)",
+	}
+	if strings.Join(missing, "\n") != strings.Join(want, "\n") {
+		t.Errorf("missing = %q, want %q", missing, want)
+	}
+}
+
+func TestCheckDoneFrontMatterAcceptance(t *testing.T) {
+	policy := DonePolicy{RequireEvidenceForIntents: []string{}}
+	cases := []struct {
+		name     string
+		criteria []string
+		body     string
+		want     []string
+	}{
+		{
+			name:     "front matter",
+			criteria: []string{"[x] uploads over 2 GiB get 413", "[ ] sizes parsed as int64", "error names the limit"},
+			want: []string{
+				"acceptance criterion not checked: sizes parsed as int64",
+				"acceptance criterion not checked: error names the limit",
+			},
+		},
+		{
+			name:     "front matter all checked",
+			criteria: []string{"[X] uploads over 2 GiB get 413"},
+		},
+		{
+			name: "body checked",
+			body: "## Acceptance Criteria\n- [x] no crash\n",
+		},
+		{
+			name: "none",
+			body: "## Notes\n- [x] unrelated\n",
+			want: []string{"no acceptance criteria found (add - [ ] items under ## Acceptance Criteria or acceptance_criteria front matter)"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			wi := NewWorkItemFile("WI-001", "Fix upload", nil)
+			wi.Meta.AcceptanceCriteria = tc.criteria
+			wi.Body = tc.body
+			missing, err := checkDone(wi, "handoff", State{}, Context{}, policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(missing, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("missing = %q, want %q", missing, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Excerpt modes for ExcerptPolicy.Mode.
+const (
+	ExcerptHead = "head"
+	ExcerptTail = "tail"
+	ExcerptGrep = "grep"
+)
+
+const (
+	defaultExcerptLines    = 20
+	defaultExcerptContext  = 3
+	defaultExcerptMaxBytes = 2000
+	// defaultExcerptsMaxBytes bounds all excerpts of one prompt together.
+	defaultExcerptsMaxBytes = 8000
+)
+
+// ExcerptPolicy opts evidence into prompt excerpts. Evidence stays paths-only unless a profile
+// lists policies; the first policy whose paths match an evidence file applies to it.
+type ExcerptPolicy struct {
+	// Paths are globs matched against the evidence path or its base name; empty matches all.
+	Paths []string `yaml:"paths,omitempty"`
+	Mode  string   `yaml:"mode"`
+	// Lines is the head/tail line count.
+	Lines int `yaml:"lines,omitempty"`
+	// Patterns are regular expressions for grep mode, e.g. FAIL|panic|ERROR.
+	Patterns []string `yaml:"patterns,omitempty"`
+	// Context is the number of lines kept around each grep match.
+	Context int `yaml:"context,omitempty"`
+	// MaxBytes and MaxTokens cap each excerpt; MaxBytes defaults to 2000.
+	MaxBytes  int `yaml:"max_bytes,omitempty"`
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+}
+
+// EvidenceExcerpt is a fenced slice of an evidence file for the prompt.
+type EvidenceExcerpt struct {
+	Path  string
+	Label string
+	Text  string
+	// Fence is a backtick run longer than any inside Text.
+	Fence string
+}
+
+func validateExcerptPolicies(policies []ExcerptPolicy) error {
+	for i, p := range policies {
+		switch p.Mode {
+		case ExcerptHead, ExcerptTail:
+		case ExcerptGrep:
+			if len(p.Patterns) == 0 {
+				return fmt.Errorf("evidence_excerpts[%d]: grep mode needs patterns", i)
+			}
+			for _, pat := range p.Patterns {
+				if _, err := regexp.Compile(pat); err != nil {
+					return fmt.Errorf("evidence_excerpts[%d]: %w", i, err)
+				}
+			}
+		default:
+			return fmt.Errorf("evidence_excerpts[%d]: unknown mode %q (known: head, tail, grep)", i, p.Mode)
+		}
+		for _, glob := range p.Paths {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("evidence_excerpts[%d]: bad path glob %q: %w", i, glob, err)
+			}
+		}
+	}
+	return nil
+}
+
+// evidenceExcerpts builds excerpts for evidence matched by a policy; unreadable or binary files are
+// skipped. Excerpts that would take the total over maxTotal bytes (default 8000) are left out and counted.
+func evidenceExcerpts(evidence []string, policies []ExcerptPolicy, maxTotal int, tok Tokenizer) ([]EvidenceExcerpt, int) {
+	if len(policies) == 0 {
+		return nil, 0
+	}
+	if maxTotal <= 0 {
+		maxTotal = defaultExcerptsMaxBytes
+	}
+	var out []EvidenceExcerpt
+	total, omitted := 0, 0
+	for _, rel := range evidence {
+		policy, ok := matchExcerptPolicy(rel, policies)
+		if !ok {
+			continue
+		}
+		data, err := ReadEvidence(rel)
+		if err != nil || IsBinary(data) {
+			continue
+		}
+		text, label := excerpt(string(data), policy, tok)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if total+len(text) > maxTotal {
+			omitted++
+			continue
+		}
+		total += len(text)
+		out = append(out, EvidenceExcerpt{
+			Path:  rel,
+			Label: label,
+			Text:  text,
+			Fence: fenceFor(text),
+		})
+	}
+	return out, omitted
+}
+
+func matchExcerptPolicy(rel string, policies []ExcerptPolicy) (ExcerptPolicy, bool) {
+	// Compressed evidence matches the globs of its original name.
+	rel = strings.TrimSuffix(rel, gzipExt)
+	for _, p := range policies {
+		if len(p.Paths) == 0 {
+			return p, true
+		}
+		for _, glob := range p.Paths {
+			if ok, _ := path.Match(glob, rel); ok {
+				return p, true
+			}
+			if ok, _ := path.Match(glob, path.Base(rel)); ok {
+				return p, true
+			}
+		}
+	}
+	return ExcerptPolicy{}, false
+}
+
+// excerptMarker stands in for lines cut to fit the excerpt caps.
+const excerptMarker = "... (truncated)"
+
+// excerpt selects lines per the policy and cuts them to fit its caps. Head drops lines from the
+// end, tail from the front, and grep drops whole match windows from the end, so the lines the
+// policy asked for are the last to go.
+func excerpt(text string, p ExcerptPolicy, tok Tokenizer) (string, string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	fits := excerptFits(p, tok)
+	n := p.Lines
+	if n <= 0 {
+		n = defaultExcerptLines
+	}
+	switch p.Mode {
+	case ExcerptHead:
+		kept, cut := capHead(lines[:min(n, len(lines))], fits)
+		return cut, fmt.Sprintf("head, lines 1-%d of %d", len(kept), len(lines))
+	case ExcerptTail:
+		kept, cut := capTail(lines[max(len(lines)-n, 0):], fits)
+		return cut, fmt.Sprintf("tail, lines %d-%d of %d", len(lines)-len(kept)+1, len(lines), len(lines))
+	}
+
+	re := regexp.MustCompile(strings.Join(p.Patterns, "|"))
+	context := p.Context
+	if context <= 0 {
+		context = defaultExcerptContext
+	}
+	var windows []string
+	var b strings.Builder
+	matches, last := 0, -1
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		matches++
+		start := max(i-context, last+1)
+		end := min(i+context, len(lines)-1)
+		if last >= 0 && start > last+1 {
+			windows = append(windows, strings.TrimRight(b.String(), "\n"))
+			b.Reset()
+		}
+		for j := start; j <= end; j++ {
+			fmt.Fprintf(&b, "%d: %s\n", j+1, lines[j])
+		}
+		last = max(last, end)
+	}
+	if b.Len() > 0 {
+		windows = append(windows, strings.TrimRight(b.String(), "\n"))
+	}
+	return capWindows(windows, fits), fmt.Sprintf("%d match(es) for %s", matches, strings.Join(p.Patterns, "|"))
+}
+
+func excerptFits(p ExcerptPolicy, tok Tokenizer) func(string) bool {
+	maxBytes := p.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultExcerptMaxBytes
+	}
+	return func(s string) bool {
+		return len(s) <= maxBytes && (p.MaxTokens <= 0 || tok.Count(s) <= p.MaxTokens)
+	}
+}
+
+// capHead drops lines from the end until the excerpt fits, marking the cut last.
+func capHead(lines []string, fits func(string) bool) ([]string, string) {
+	if text := strings.Join(lines, "\n"); fits(text) {
+		return lines, text
+	}
+	for len(lines) > 0 {
+		lines = lines[:len(lines)-1]
+		if candidate := strings.Join(append(lines[:len(lines):len(lines)], excerptMarker), "\n"); fits(candidate) {
+			return lines, candidate
+		}
+	}
+	return nil, excerptMarker
+}
+
+// capTail drops lines from the front until the excerpt fits, marking the cut first.
+func capTail(lines []string, fits func(string) bool) ([]string, string) {
+	if text := strings.Join(lines, "\n"); fits(text) {
+		return lines, text
+	}
+	for len(lines) > 0 {
+		lines = lines[1:]
+		if candidate := excerptMarker + "\n" + strings.Join(lines, "\n"); fits(candidate) {
+			return lines, candidate
+		}
+	}
+	return nil, excerptMarker
+}
+
+// capWindows keeps leading grep windows that fit, noting how many were dropped; when not even
+// the first window fits with the note, the windows are cut like a head excerpt.
+func capWindows(windows []string, fits func(string) bool) string {
+	const sep = "\n...\n"
+	if text := strings.Join(windows, sep); fits(text) {
+		return text
+	}
+	for keep := len(windows) - 1; keep > 0; keep-- {
+		candidate := strings.Join(windows[:keep], sep) + fmt.Sprintf("\n... (truncated: %d more match window(s))", len(windows)-keep)
+		if fits(candidate) {
+			return candidate
+		}
+	}
+	_, text := capHead(strings.Split(strings.Join(windows, sep), "\n"), fits)
+	return text
+}
+
+func fenceFor(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// IsBinary reports whether data looks binary (a NUL byte in the first 8KB).
+func IsBinary(data []byte) bool {
+	if len(data) > 8192 {
+		data = data[:8192]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
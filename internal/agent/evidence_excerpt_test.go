@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func numberedLog(n int, last string) string {
+	var b strings.Builder
+	for i := 1; i < n; i++ {
+		fmt.Fprintf(&b, "line %d of the build output\n", i)
+	}
+	b.WriteString(last + "\n")
+	return b.String()
+}
+
+func TestExcerptCaps(t *testing.T) {
+	tok, _ := LoadTokenizer("chars4")
+	log := numberedLog(501, "FAIL final line")
+	tests := []struct {
+		name        string
+		policy      ExcerptPolicy
+		wantPrefix  string
+		wantSuffix  string
+		wantLabel   string
+		wantMissing string
+	}{
+		{
+			name:       "head fits",
+			policy:     ExcerptPolicy{Mode: ExcerptHead, Lines: 2},
+			wantPrefix: "line 1 of",
+			wantSuffix: "line 2 of the build output",
+			wantLabel:  "head, lines 1-2 of 501",
+		},
+		{
+			name:        "head keeps the start",
+			policy:      ExcerptPolicy{Mode: ExcerptHead, Lines: 400},
+			wantPrefix:  "line 1 of",
+			wantSuffix:  "\n" + excerptMarker,
+			wantMissing: "FAIL final line",
+		},
+		{
+			name:        "tail keeps the end",
+			policy:      ExcerptPolicy{Mode: ExcerptTail, Lines: 400},
+			wantPrefix:  excerptMarker + "\n",
+			wantSuffix:  "FAIL final line",
+			wantLabel:   "tail, lines 434-501 of 501",
+			wantMissing: "line 102 of",
+		},
+		{
+			name:       "tail fits",
+			policy:     ExcerptPolicy{Mode: ExcerptTail, Lines: 3},
+			wantPrefix: "line 499 of",
+			wantSuffix: "FAIL final line",
+			wantLabel:  "tail, lines 499-501 of 501",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, label := excerpt(log, tt.policy, tok)
+			if len(text) > defaultExcerptMaxBytes {
+				t.Errorf("excerpt is %d bytes, over the %d cap", len(text), defaultExcerptMaxBytes)
+			}
+			if !strings.HasPrefix(text, tt.wantPrefix) || !strings.HasSuffix(text, tt.wantSuffix) {
+				t.Errorf("excerpt = %q..%q, want prefix %q and suffix %q", head(text), tail(text), tt.wantPrefix, tt.wantSuffix)
+			}
+			if tt.wantLabel != "" && label != tt.wantLabel {
+				t.Errorf("label = %q, want %q", label, tt.wantLabel)
+			}
+			if tt.wantMissing != "" && strings.Contains(text, tt.wantMissing) {
+				t.Errorf("excerpt unexpectedly contains %q", tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestExcerptGrepDropsWholeWindows(t *testing.T) {
+	tok, _ := LoadTokenizer("chars4")
+	var b strings.Builder
+	for i := 1; i <= 100; i++ {
+		if i%20 == 0 {
+			fmt.Fprintf(&b, "FAIL case %d\n", i)
+		} else {
+			fmt.Fprintf(&b, "ok %d\n", i)
+		}
+	}
+	policy := ExcerptPolicy{Mode: ExcerptGrep, Patterns: []string{"FAIL"}, Context: 1}
+
+	text, label := excerpt(b.String(), policy, tok)
+	if label != "5 match(es) for FAIL" || strings.Count(text, "\n...\n") != 4 {
+		t.Fatalf("uncapped grep = %q (%s), want 5 windows", text, label)
+	}
+
+	policy.MaxBytes = 80
+	text, _ = excerpt(b.String(), policy, tok)
+	want := "19: ok 19\n20: FAIL case 20\n21: ok 21\n... (truncated: 4 more match window(s))"
+	if text != want {
+		t.Errorf("capped grep = %q, want %q", text, want)
+	}
+
+	policy.MaxBytes = 45
+	text, _ = excerpt(b.String(), policy, tok)
+	want = "19: ok 19\n20: FAIL case 20\n" + excerptMarker
+	if text != want {
+		t.Errorf("grep capped inside a window = %q, want %q", text, want)
+	}
+}
+
+func head(s string) string { return s[:min(len(s), 30)] }
+func tail(s string) string { return s[max(len(s)-30, 0):] }
+
+func TestEvidenceExcerptsTotalCap(t *testing.T) {
+	inTempRepo(t)
+	tok, _ := LoadTokenizer("chars4")
+	evidence := []string{"evidence/a.log", "evidence/b.log", "evidence/c.log", "evidence/d.log"}
+	sizes := []int{30, 40, 60, 10}
+	for i, rel := range evidence {
+		if err := writeTestEvidence(rel, []byte(strings.Repeat("x", sizes[i]-1)+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policies := []ExcerptPolicy{{Mode: ExcerptHead, Lines: 5}}
+	cases := []struct {
+		maxTotal    int
+		wantPaths   string
+		wantOmitted int
+	}{
+		{0, "evidence/a.log evidence/b.log evidence/c.log evidence/d.log", 0},
+		{100, "evidence/a.log evidence/b.log evidence/d.log", 1},
+		{35, "evidence/a.log", 3},
+		{5, "", 4},
+	}
+	for _, tc := range cases {
+		excerpts, omitted := evidenceExcerpts(evidence, policies, tc.maxTotal, tok)
+		var paths []string
+		total := 0
+		for _, e := range excerpts {
+			paths = append(paths, e.Path)
+			total += len(e.Text)
+		}
+		if strings.Join(paths, " ") != tc.wantPaths || omitted != tc.wantOmitted {
+			t.Errorf("max %d: excerpts %q, omitted %d; want %q, %d", tc.maxTotal, paths, omitted, tc.wantPaths, tc.wantOmitted)
+		}
+		if tc.maxTotal > 0 && total > tc.maxTotal {
+			t.Errorf("max %d: excerpts total %d bytes", tc.maxTotal, total)
+		}
+	}
+}
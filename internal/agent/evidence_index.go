@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const evidenceIndexFile = "index.yaml"
+
+// EvidenceEntry is the index record for one stored evidence file.
+type EvidenceEntry struct {
+	// Path is relative to .agent.
+	Path   string `yaml:"path"`
+	SHA256 string `yaml:"sha256"`
+	Size   int64  `yaml:"size"`
+	Type   string `yaml:"type"`
+	// Source is where the evidence came from, e.g. the original file path.
+	Source    string    `yaml:"source,omitempty"`
+	AddedAt   time.Time `yaml:"added_at"`
+	WorkItems []string  `yaml:"work_items,omitempty"`
+	Note      string    `yaml:"note,omitempty"`
+	// Compressed is set when the file is stored gzipped; SHA256 and Size describe the content.
+	Compressed bool `yaml:"compressed,omitempty"`
+	// Run is set for output captured by ctx evidence run.
+	Run *EvidenceRun `yaml:"run,omitempty"`
+	// Tests summarizes a go test -json or JUnit report.
+	Tests *TestReport `yaml:"tests,omitempty"`
+	// Frames are stack trace frames that map to repo files.
+	Frames []StackFrame `yaml:"frames,omitempty"`
+}
+
+// EvidenceIndex is .agent/evidence/index.yaml.
+type EvidenceIndex struct {
+	Entries []EvidenceEntry `yaml:"entries"`
+}
+
+// LoadEvidenceIndex reads the evidence index; a missing index is empty.
+func LoadEvidenceIndex() (EvidenceIndex, error) {
+	var idx EvidenceIndex
+	if err := readYAML(AgentPath(evidenceDir, evidenceIndexFile), &idx); err != nil && !os.IsNotExist(err) {
+		return idx, err
+	}
+	return idx, nil
+}
+
+// SaveEvidenceIndex writes the evidence index.
+func SaveEvidenceIndex(idx EvidenceIndex) error {
+	if err := os.MkdirAll(AgentPath(evidenceDir), 0o755); err != nil {
+		return err
+	}
+	return saveYAML(AgentPath(evidenceDir, evidenceIndexFile), idx)
+}
+
+// Find returns the entry for an .agent-relative path.
+func (idx *EvidenceIndex) Find(rel string) *EvidenceEntry {
+	rel = filepath.ToSlash(rel)
+	for i := range idx.Entries {
+		if idx.Entries[i].Path == rel {
+			return &idx.Entries[i]
+		}
+	}
+	return nil
+}
+
+// findContent returns an entry with the given hash whose file still exists.
+func (idx *EvidenceIndex) findContent(sum string) *EvidenceEntry {
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if e.SHA256 != sum {
+			continue
+		}
+		if _, err := os.Stat(AgentPath(filepath.FromSlash(e.Path))); err == nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// Notes maps evidence paths to their notes.
+func (idx EvidenceIndex) Notes() map[string]string {
+	notes := map[string]string{}
+	for _, e := range idx.Entries {
+		if e.Note != "" {
+			notes[e.Path] = e.Note
+		}
+	}
+	return notes
+}
+
+func (e *EvidenceEntry) addWorkItem(id string) bool {
+	for _, w := range e.WorkItems {
+		if w == id {
+			return false
+		}
+	}
+	e.WorkItems = append(e.WorkItems, id)
+	return true
+}
+
+func contentSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// detectEvidenceType guesses a media type from the extension, falling back to content sniffing.
+func detectEvidenceType(name string, data []byte) string {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	if t == "" {
+		t = http.DetectContentType(data)
+	}
+	if media, _, err := mime.ParseMediaType(t); err == nil {
+		return media
+	}
+	return t
+}
+
+// evidenceNoteList appends index notes to evidence paths for the prompt.
+func evidenceNoteList(items []string, notes map[string]string) []string {
+	if len(notes) == 0 {
+		return items
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item
+		if note := notes[item]; note != "" {
+			out[i] = item + " — " + note
+		}
+	}
+	return out
+}
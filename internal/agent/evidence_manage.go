@@ -0,0 +1,273 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EvidenceInfo describes an evidence file for listing.
+type EvidenceInfo struct {
+	// Path is relative to .agent.
+	Path      string
+	Size      int64
+	Type      string
+	Note      string
+	WorkItems []string
+	// Missing is set when a work item links a file that no longer exists.
+	Missing bool
+}
+
+// EvidenceGCResult reports what ctx evidence gc removed (or would remove).
+type EvidenceGCResult struct {
+	Removed []string
+	Bytes   int64
+	// Pruned counts index entries dropped.
+	Pruned int
+}
+
+// NormalizeEvidencePath accepts evidence/x, .agent/evidence/x or a bare x and returns evidence/x.
+func NormalizeEvidencePath(arg string) string {
+	p := path.Clean(filepath.ToSlash(arg))
+	p = strings.TrimPrefix(p, agentDir+"/")
+	if !strings.HasPrefix(p, evidenceDir+"/") {
+		p = evidenceDir + "/" + p
+	}
+	return p
+}
+
+// evidenceRefs maps evidence paths to the work items that link them.
+func evidenceRefs() (map[string][]string, error) {
+	ids, err := ListWorkItems()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	refs := map[string][]string{}
+	for _, id := range ids {
+		wi, err := LoadWorkItem(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		for _, e := range wi.Meta.Evidence {
+			e = filepath.ToSlash(e)
+			refs[e] = append(refs[e], id)
+		}
+	}
+	return refs, nil
+}
+
+// gateEvidenceRefs returns the evidence recorded as the last output of each quality gate.
+// Gate output is not linked to a work item when none is active, so gc must keep it this way.
+func gateEvidenceRefs() (map[string]bool, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	refs := map[string]bool{}
+	for _, r := range state.Gates {
+		if r.Evidence != "" {
+			refs[filepath.ToSlash(r.Evidence)] = true
+		}
+	}
+	return refs, nil
+}
+
+// storedEvidenceFiles lists the .agent-relative paths of files under .agent/evidence, excluding the index.
+func storedEvidenceFiles() ([]string, error) {
+	var files []string
+	root := AgentPath(evidenceDir)
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || p == AgentPath(evidenceDir, evidenceIndexFile) {
+			return nil
+		}
+		rel, err := filepath.Rel(agentDir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// ListEvidence lists a work item's evidence, or with all every stored or linked file.
+func ListEvidence(id string, all bool) ([]EvidenceInfo, error) {
+	refs, err := evidenceRefs()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	if all {
+		files, err := storedEvidenceFiles()
+		if err != nil {
+			return nil, err
+		}
+		for p := range refs {
+			files = append(files, p)
+		}
+		sort.Strings(files)
+		paths = dedupe(files)
+	} else {
+		wi, err := LoadWorkItem(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range wi.Meta.Evidence {
+			paths = append(paths, filepath.ToSlash(e))
+		}
+	}
+
+	infos := make([]EvidenceInfo, 0, len(paths))
+	for _, p := range paths {
+		info := EvidenceInfo{Path: p, WorkItems: refs[p]}
+		if stat, err := os.Stat(AgentPath(filepath.FromSlash(p))); err == nil {
+			info.Size = stat.Size()
+		} else {
+			info.Missing = true
+		}
+		if entry := idx.Find(p); entry != nil {
+			info.Type = entry.Type
+			info.Note = entry.Note
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// DetachEvidence unlinks an evidence path from a work item; the file stays until gc.
+func DetachEvidence(id, rel string) error {
+	wi, err := LoadWorkItem(id)
+	if err != nil {
+		return err
+	}
+	kept := wi.Meta.Evidence[:0]
+	found := false
+	for _, e := range wi.Meta.Evidence {
+		if filepath.ToSlash(e) == rel {
+			found = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if !found {
+		return fmt.Errorf("%s is not linked to %s", rel, id)
+	}
+	wi.Meta.Evidence = kept
+	if err := SaveWorkItem(wi); err != nil {
+		return err
+	}
+
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return err
+	}
+	entry := idx.Find(rel)
+	if entry == nil {
+		return nil
+	}
+	items := entry.WorkItems[:0]
+	for _, w := range entry.WorkItems {
+		if w != id {
+			items = append(items, w)
+		}
+	}
+	entry.WorkItems = items
+	return SaveEvidenceIndex(idx)
+}
+
+// GCEvidence deletes evidence files that no work item links and no gate result records,
+// and prunes the index to match.
+func GCEvidence(dryRun bool) (EvidenceGCResult, error) {
+	var result EvidenceGCResult
+	refs, err := evidenceRefs()
+	if err != nil {
+		return result, err
+	}
+	gateRefs, err := gateEvidenceRefs()
+	if err != nil {
+		return result, err
+	}
+	files, err := storedEvidenceFiles()
+	if err != nil {
+		return result, err
+	}
+	for _, rel := range files {
+		if len(refs[rel]) > 0 || gateRefs[rel] {
+			continue
+		}
+		full := AgentPath(filepath.FromSlash(rel))
+		if stat, err := os.Stat(full); err == nil {
+			result.Bytes += stat.Size()
+		}
+		result.Removed = append(result.Removed, rel)
+		if !dryRun {
+			if err := os.Remove(full); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return result, err
+	}
+	kept := idx.Entries[:0]
+	for _, e := range idx.Entries {
+		if len(refs[e.Path]) == 0 && !gateRefs[e.Path] {
+			result.Pruned++
+			continue
+		}
+		if _, err := os.Stat(AgentPath(filepath.FromSlash(e.Path))); err != nil {
+			result.Pruned++
+			continue
+		}
+		e.WorkItems = refs[e.Path]
+		kept = append(kept, e)
+	}
+	if dryRun {
+		return result, nil
+	}
+	idx.Entries = kept
+	if err := SaveEvidenceIndex(idx); err != nil {
+		return result, err
+	}
+	return result, removeEmptyDirs(AgentPath(evidenceDir))
+}
+
+// removeEmptyDirs deletes empty directories below root, deepest first.
+func removeEmptyDirs(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != root {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
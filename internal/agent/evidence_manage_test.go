@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGCEvidenceKeepsGateOutput(t *testing.T) {
+	inTempRepo(t)
+	for _, rel := range []string{"evidence/gate-tests.log", "evidence/orphan.log", "evidence/linked.log"} {
+		if err := writeTestEvidence(rel, []byte(rel+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveWorkItem(NewWorkItemFile("WI-001", "fix linked bug", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := AttachEvidence("WI-001", "evidence/linked.log"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Gates = map[string]GateResult{"tests": {Passed: true, Evidence: "evidence/gate-tests.log"}}
+	if err := SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := GCEvidence(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != "evidence/orphan.log" {
+		t.Errorf("removed %v, want only evidence/orphan.log", result.Removed)
+	}
+	for _, rel := range []string{"evidence/gate-tests.log", "evidence/linked.log"} {
+		if _, err := os.Stat(AgentPath(rel)); err != nil {
+			t.Errorf("%s was deleted", rel)
+		}
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Find("evidence/gate-tests.log") == nil || idx.Find("evidence/orphan.log") != nil {
+		t.Errorf("index after gc = %+v", idx.Entries)
+	}
+}
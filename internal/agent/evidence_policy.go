@@ -0,0 +1,307 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const gzipExt = ".gz"
+
+// EvidencePruneResult reports what ctx evidence prune changed (or would change).
+type EvidencePruneResult struct {
+	Expired    []string
+	Compressed []string
+	// Warnings lists files and totals still over quota.
+	Warnings []string
+	// Saved is the number of bytes freed.
+	Saved int64
+}
+
+// ReadEvidence reads an evidence file by its .agent-relative path, decompressing .gz files.
+func ReadEvidence(rel string) ([]byte, error) {
+	data, err := os.ReadFile(AgentPath(filepath.FromSlash(rel)))
+	if err != nil || !strings.HasSuffix(rel, gzipExt) {
+		return data, err
+	}
+	return gunzip(data)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// shouldCompress reports whether text evidence is over the compression threshold.
+func (p EvidencePolicy) shouldCompress(name string, data []byte) bool {
+	return p.CompressOverBytes > 0 && int64(len(data)) > p.CompressOverBytes &&
+		!strings.HasSuffix(name, gzipExt) && !IsBinary(data)
+}
+
+// checkQuota returns a problem when adding size bytes would exceed a quota, or "" when it fits.
+func (p EvidencePolicy) checkQuota(name string, size int64) (string, error) {
+	if p.MaxFileBytes > 0 && size > p.MaxFileBytes {
+		return fmt.Sprintf("%s is %d bytes, over evidence.max_file_bytes (%d)", name, size, p.MaxFileBytes), nil
+	}
+	if p.MaxTotalBytes > 0 {
+		total, err := evidenceTotalBytes()
+		if err != nil {
+			return "", err
+		}
+		if total+size > p.MaxTotalBytes {
+			return fmt.Sprintf("adding %s brings evidence to %d bytes, over evidence.max_total_bytes (%d)", name, total+size, p.MaxTotalBytes), nil
+		}
+	}
+	return "", nil
+}
+
+// evidenceTotalBytes sums the stored size of all evidence files.
+func evidenceTotalBytes() (int64, error) {
+	files, err := storedEvidenceFiles()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, rel := range files {
+		if stat, err := os.Stat(AgentPath(filepath.FromSlash(rel))); err == nil {
+			total += stat.Size()
+		}
+	}
+	return total, nil
+}
+
+// doneSince returns when a done work item was finished, or false if it is not done.
+func doneSince(w WorkItem) (time.Time, bool) {
+	if w.Status != "done" {
+		return time.Time{}, false
+	}
+	if w.DoneAt != nil {
+		return *w.DoneAt, true
+	}
+	if n := len(w.Sessions); n > 0 && w.Sessions[n-1].StoppedAt != nil {
+		return *w.Sessions[n-1].StoppedAt, true
+	}
+	return w.CreatedAt, true
+}
+
+// PruneEvidence applies .agent/policy.yaml: it expires evidence linked only to long-done items,
+// compresses large text files, and reports anything still over quota.
+func PruneEvidence(dryRun bool) (EvidencePruneResult, error) {
+	var result EvidencePruneResult
+	policy, err := LoadPolicy()
+	if err != nil {
+		return result, err
+	}
+	p := policy.Evidence
+	refs, err := evidenceRefs()
+	if err != nil {
+		return result, err
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return result, err
+	}
+
+	if p.ExpireDoneAfterDays > 0 {
+		cutoff := time.Now().Add(-time.Duration(p.ExpireDoneAfterDays) * 24 * time.Hour)
+		items := map[string]WorkItem{}
+		paths := make([]string, 0, len(refs))
+		for rel := range refs {
+			paths = append(paths, rel)
+		}
+		sort.Strings(paths)
+		for _, rel := range paths {
+			ids := refs[rel]
+			expired := true
+			for _, id := range ids {
+				w, ok := items[id]
+				if !ok {
+					wi, err := LoadWorkItem(id)
+					if err != nil {
+						return result, err
+					}
+					w = wi.Meta
+					items[id] = w
+				}
+				if since, done := doneSince(w); !done || since.After(cutoff) {
+					expired = false
+					break
+				}
+			}
+			if !expired {
+				continue
+			}
+			result.Expired = append(result.Expired, rel)
+			if stat, err := os.Stat(AgentPath(filepath.FromSlash(rel))); err == nil {
+				result.Saved += stat.Size()
+			}
+			if dryRun {
+				continue
+			}
+			for _, id := range ids {
+				if err := DetachEvidence(id, rel); err != nil {
+					return result, err
+				}
+			}
+			if err := os.Remove(AgentPath(filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+				return result, err
+			}
+		}
+		if !dryRun && len(result.Expired) > 0 {
+			// DetachEvidence rewrote the index.
+			if idx, err = LoadEvidenceIndex(); err != nil {
+				return result, err
+			}
+			kept := idx.Entries[:0]
+			for _, e := range idx.Entries {
+				if !containsString(result.Expired, e.Path) {
+					kept = append(kept, e)
+				}
+			}
+			idx.Entries = kept
+		}
+	}
+
+	if p.CompressOverBytes > 0 {
+		files, err := storedEvidenceFiles()
+		if err != nil {
+			return result, err
+		}
+		for _, rel := range files {
+			if containsString(result.Expired, rel) {
+				continue
+			}
+			full := AgentPath(filepath.FromSlash(rel))
+			data, err := os.ReadFile(full)
+			if err != nil {
+				return result, err
+			}
+			if !p.shouldCompress(rel, data) {
+				continue
+			}
+			packed, err := gzipBytes(data)
+			if err != nil {
+				return result, err
+			}
+			result.Compressed = append(result.Compressed, rel)
+			result.Saved += int64(len(data) - len(packed))
+			if dryRun {
+				continue
+			}
+			if err := compressEvidenceFile(rel, packed, refs[rel], &idx); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if !dryRun {
+		if err := SaveEvidenceIndex(idx); err != nil {
+			return result, err
+		}
+	}
+
+	if p.MaxFileBytes > 0 || p.MaxTotalBytes > 0 {
+		files, err := storedEvidenceFiles()
+		if err != nil {
+			return result, err
+		}
+		var total int64
+		for _, rel := range files {
+			stat, err := os.Stat(AgentPath(filepath.FromSlash(rel)))
+			if err != nil {
+				continue
+			}
+			total += stat.Size()
+			if p.MaxFileBytes > 0 && stat.Size() > p.MaxFileBytes {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s is %d bytes, over evidence.max_file_bytes (%d)", rel, stat.Size(), p.MaxFileBytes))
+			}
+		}
+		if p.MaxTotalBytes > 0 && total > p.MaxTotalBytes {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("evidence totals %d bytes, over evidence.max_total_bytes (%d)", total, p.MaxTotalBytes))
+		}
+	}
+	return result, nil
+}
+
+// compressEvidenceFile replaces rel with rel.gz and updates work item links, gate results and the index.
+func compressEvidenceFile(rel string, packed []byte, ids []string, idx *EvidenceIndex) error {
+	dest := AgentPath(filepath.FromSlash(rel + gzipExt))
+	if _, err := os.Stat(dest); err == nil {
+		dest = uniquePath(dest)
+	}
+	if err := os.WriteFile(dest, packed, 0o644); err != nil {
+		return err
+	}
+	newRel, err := filepath.Rel(agentDir, dest)
+	if err != nil {
+		return err
+	}
+	newRel = filepath.ToSlash(newRel)
+	for _, id := range ids {
+		wi, err := LoadWorkItem(id)
+		if err != nil {
+			return err
+		}
+		for i, e := range wi.Meta.Evidence {
+			if filepath.ToSlash(e) == rel {
+				wi.Meta.Evidence[i] = newRel
+			}
+		}
+		if err := SaveWorkItem(wi); err != nil {
+			return err
+		}
+	}
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	moved := false
+	for key, r := range state.Gates {
+		if filepath.ToSlash(r.Evidence) == rel {
+			r.Evidence = newRel
+			state.Gates[key] = r
+			moved = true
+		}
+	}
+	if moved {
+		if err := SaveState(state); err != nil {
+			return err
+		}
+	}
+	if entry := idx.Find(rel); entry != nil {
+		entry.Path = newRel
+		entry.Compressed = true
+	}
+	return os.Remove(AgentPath(filepath.FromSlash(rel)))
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPruneCompressesGateOutputAndGCKeepsIt(t *testing.T) {
+	inTempRepo(t)
+	log := strings.Repeat("--- FAIL: TestUpload\n", 20)
+	if err := writeTestEvidence("evidence/gate-tests.log", []byte(log)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(AgentPath(policyFile), []byte("evidence:\n  compress_over_bytes: 100\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Gates = map[string]GateResult{"tests": {Evidence: "evidence/gate-tests.log"}}
+	if err := SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PruneEvidence(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Compressed) != 1 {
+		t.Fatalf("compressed %v, want the gate log", result.Compressed)
+	}
+	if state, err = LoadState(); err != nil {
+		t.Fatal(err)
+	}
+	const packed = "evidence/gate-tests.log.gz"
+	if got := state.Gates["tests"].Evidence; got != packed {
+		t.Fatalf("gate evidence = %q, want %q", got, packed)
+	}
+
+	gc, err := GCEvidence(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gc.Removed) != 0 {
+		t.Errorf("gc removed %v, want nothing", gc.Removed)
+	}
+	data, err := ReadEvidence(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != log {
+		t.Errorf("compressed gate log does not round-trip")
+	}
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EvidenceRun records the command that produced an evidence file.
+type EvidenceRun struct {
+	Command  string        `yaml:"command"`
+	ExitCode int           `yaml:"exit_code"`
+	Duration time.Duration `yaml:"duration"`
+	// Dir is the working directory, relative to the repo root.
+	Dir string `yaml:"dir"`
+}
+
+// RunEvidenceResult is a captured command run.
+type RunEvidenceResult struct {
+	Stored StoredEvidence
+	Run    EvidenceRun
+}
+
+// RunEvidence runs a command in dir, tees its combined output to out, and stores the output as
+// evidence with the run recorded in the index. A nonzero exit is reported in Run, not as an error.
+func RunEvidence(workItemID string, args []string, dir, note string, out io.Writer) (RunEvidenceResult, error) {
+	if len(args) == 0 {
+		return RunEvidenceResult{}, fmt.Errorf("no command to run")
+	}
+	if dir == "" {
+		dir = "."
+	}
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = dir
+	c.Stdin = os.Stdin
+	start := time.Now()
+	output, exitCode, duration, err := captureCommand(c, out)
+	if err != nil {
+		return RunEvidenceResult{}, fmt.Errorf("run %s: %w", args[0], err)
+	}
+	run := EvidenceRun{
+		Command:  shellJoin(args),
+		ExitCode: exitCode,
+		Duration: duration,
+		Dir:      filepath.ToSlash(filepath.Clean(dir)),
+	}
+
+	name := fmt.Sprintf("%s-run-%s-%s.log", workItemID, sanitizeBranch(strings.ToLower(filepath.Base(args[0]))), start.UTC().Format("20060102T150405Z"))
+	stored, err := WriteEvidence(name, output, EvidenceEntry{Source: "command", Note: note, Run: &run})
+	if err != nil {
+		return RunEvidenceResult{}, err
+	}
+	if err := AttachEvidence(workItemID, stored.Path); err != nil {
+		return RunEvidenceResult{}, err
+	}
+	return RunEvidenceResult{Stored: stored, Run: run}, nil
+}
+
+// captureCommand runs c with combined output teed to out. A nonzero exit is returned as exitCode;
+// err is set only when the command could not run.
+func captureCommand(c *exec.Cmd, out io.Writer) ([]byte, int, time.Duration, error) {
+	var buf bytes.Buffer
+	tee := io.MultiWriter(&buf, out)
+	c.Stdout = tee
+	c.Stderr = tee
+	start := time.Now()
+	err := c.Run()
+	duration := time.Since(start).Round(time.Millisecond)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return buf.Bytes(), exitErr.ExitCode(), duration, nil
+	}
+	return buf.Bytes(), 0, duration, err
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./=:@%+,-]+$`)
+
+// shellJoin renders args as a copy-pastable POSIX shell command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if shellSafe.MatchString(a) {
+			quoted[i] = a
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
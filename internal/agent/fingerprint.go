@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// repoFingerprint hashes the state of the repo's working tree, excluding .agent, so gate results
+// can tell whether anything changed since they ran. In a git work tree it covers HEAD, the diff
+// against HEAD and untracked files that .gitignore does not exclude; elsewhere, or before the
+// first commit, it hashes every file walkRepoFiles visits.
+func repoFingerprint() (string, error) {
+	h := sha256.New()
+	if head, err := gitOutput("rev-parse", "--verify", "HEAD"); err == nil {
+		diff, err := gitOutput("diff", "--binary", "HEAD", "--", ".", ":(exclude)"+agentDir)
+		if err != nil {
+			return "", err
+		}
+		untracked, err := gitOutput("ls-files", "-z", "--others", "--exclude-standard", "--", ".", ":(exclude)"+agentDir)
+		if err != nil {
+			return "", err
+		}
+		h.Write(head)
+		h.Write(diff)
+		for _, rel := range bytes.Split(bytes.TrimRight(untracked, "\x00"), []byte{0}) {
+			if len(rel) > 0 {
+				hashRepoFile(h, string(rel))
+			}
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	err := walkRepoFiles(func(rel string, d os.DirEntry) error {
+		hashRepoFile(h, rel)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashRepoFile adds a file's path and content to h; unreadable files count by path only.
+func hashRepoFile(h io.Writer, rel string) {
+	fmt.Fprintf(h, "%s\x00", rel)
+	f, err := os.Open(rel)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = io.Copy(h, f)
+}
+
+func gitOutput(args ...string) ([]byte, error) {
+	return exec.Command("git", args...).Output()
+}
@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultGateTimeout applies to executable gates without a timeout.
+const defaultGateTimeout = 10 * time.Minute
+
+// QualityGate is a quality bar; gates with a command can be run with ctx gate run.
+type QualityGate struct {
+	// Name identifies the gate for ctx gate run; it defaults to a slug of the description.
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Command runs through the system shell from the repo root.
+	Command    string        `yaml:"command,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
+	ExpectExit int           `yaml:"expect_exit,omitempty"`
+
+	// set records the keys present in YAML, so extends can tell an explicit 0 from unset.
+	set map[string]bool
+}
+
+// UnmarshalYAML also accepts the plain-string form, read as a description-only gate.
+func (g *QualityGate) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		g.Description = node.Value
+		g.set = map[string]bool{"description": true}
+		return nil
+	}
+	type plain QualityGate
+	if err := node.Decode((*plain)(g)); err != nil {
+		return err
+	}
+	g.set = map[string]bool{}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			g.set[node.Content[i].Value] = true
+		}
+	}
+	return nil
+}
+
+// sets reports whether the gate sets key. Gates built in Go rather than decoded from YAML
+// fall back to nonZero.
+func (g QualityGate) sets(key string, nonZero bool) bool {
+	if g.set == nil {
+		return nonZero
+	}
+	return g.set[key]
+}
+
+// MarshalYAML writes description-only gates back as plain strings.
+func (g QualityGate) MarshalYAML() (any, error) {
+	if g.Name == "" && g.Command == "" && g.Timeout == 0 && g.ExpectExit == 0 {
+		return g.Description, nil
+	}
+	type plain QualityGate
+	return plain(g), nil
+}
+
+// Key returns the gate's name, or a slug of its description.
+func (g QualityGate) Key() string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return sanitizeBranch(strings.ToLower(g.Description))
+}
+
+// Executable reports whether the gate has a command.
+func (g QualityGate) Executable() bool {
+	return strings.TrimSpace(g.Command) != ""
+}
+
+// Label is the text shown for the gate in prompts.
+func (g QualityGate) Label() string {
+	if g.Description != "" {
+		return g.Description
+	}
+	return g.Name
+}
+
+// GateResult is the last run of an executable gate, kept in state.yaml.
+type GateResult struct {
+	Passed   bool          `yaml:"passed"`
+	ExitCode int           `yaml:"exit_code"`
+	TimedOut bool          `yaml:"timed_out,omitempty"`
+	Duration time.Duration `yaml:"duration"`
+	RanAt    time.Time     `yaml:"ran_at"`
+	// Evidence is the .agent-relative path of the captured output.
+	Evidence string `yaml:"evidence,omitempty"`
+	// Fingerprint hashes the working tree after the run (see repoFingerprint); ctx work done
+	// compares it with the current tree.
+	Fingerprint string `yaml:"fingerprint,omitempty"`
+}
+
+// Status renders the result as a short phrase for prompts.
+func (r GateResult) Status() string {
+	switch {
+	case r.Passed:
+		return fmt.Sprintf("passed %s", r.RanAt.Format("2006-01-02 15:04"))
+	case r.TimedOut:
+		return fmt.Sprintf("FAILED (timed out) %s", r.RanAt.Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("FAILED (exit %d) %s", r.ExitCode, r.RanAt.Format("2006-01-02 15:04"))
+}
+
+// GateRun pairs a gate with its new result.
+type GateRun struct {
+	Gate   QualityGate
+	Result GateResult
+}
+
+// stringGates wraps description-only gates, as used by built-in templates.
+func stringGates(descriptions ...string) []QualityGate {
+	gates := make([]QualityGate, len(descriptions))
+	for i, d := range descriptions {
+		gates[i] = QualityGate{Description: d}
+	}
+	return gates
+}
+
+// selectGates returns the executable gates, or the one named.
+func selectGates(gates []QualityGate, name string) ([]QualityGate, error) {
+	var out, executable []QualityGate
+	for _, g := range gates {
+		if !g.Executable() {
+			continue
+		}
+		executable = append(executable, g)
+		if name == "" || g.Key() == name {
+			out = append(out, g)
+		}
+	}
+	if len(executable) == 0 {
+		return nil, fmt.Errorf("no executable quality gates; add a command to a gate in .agent/context.yaml")
+	}
+	if len(out) == 0 {
+		keys := make([]string, len(executable))
+		for i, g := range executable {
+			keys[i] = g.Key()
+		}
+		return nil, fmt.Errorf("quality gate %q not found (executable gates: %s)", name, strings.Join(keys, ", "))
+	}
+	return out, nil
+}
+
+// RunGates runs executable gates (all, or the one named), stores each output as evidence on the
+// active work item, records results in state, and updates health.
+func RunGates(name string, out io.Writer) ([]GateRun, error) {
+	ctxFile, err := LoadContext()
+	if err != nil {
+		return nil, err
+	}
+	gates, err := selectGates(ctxFile.QualityGates, name)
+	if err != nil {
+		return nil, err
+	}
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []GateRun
+	for _, g := range gates {
+		fmt.Fprintf(out, "==> gate %s: %s\n", g.Key(), g.Command)
+		result, err := runGate(g, state.ActiveWorkItem, out)
+		if err != nil {
+			// Keep the results of gates that already ran.
+			if len(runs) > 0 {
+				if saveErr := saveGateRuns(&state, ctxFile.QualityGates, runs); saveErr != nil {
+					return runs, fmt.Errorf("%w (saving earlier gate results: %v)", err, saveErr)
+				}
+			}
+			return runs, err
+		}
+		if state.Gates == nil {
+			state.Gates = map[string]GateResult{}
+		}
+		state.Gates[g.Key()] = result
+		updateGateHealth(&state.Health, g, result)
+		runs = append(runs, GateRun{Gate: g, Result: result})
+	}
+	return runs, saveGateRuns(&state, ctxFile.QualityGates, runs)
+}
+
+// saveGateRuns stamps the runs with the tree's fingerprint, taken once after the whole batch so
+// files written by one gate do not invalidate the others, then updates health and saves state.
+func saveGateRuns(state *State, gates []QualityGate, runs []GateRun) error {
+	fingerprint, err := repoFingerprint()
+	if err != nil {
+		return err
+	}
+	for i := range runs {
+		runs[i].Result.Fingerprint = fingerprint
+		state.Gates[runs[i].Gate.Key()] = runs[i].Result
+	}
+	state.Health.Status = gateHealthStatus(state.Health, gates, state.Gates)
+	return SaveState(*state)
+}
+
+func runGate(g QualityGate, workItemID string, out io.Writer) (GateResult, error) {
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = defaultGateTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	c := exec.CommandContext(ctx, shell, flag, g.Command)
+	// Children of the shell can keep output pipes open after a timeout kill; stop waiting for them.
+	c.WaitDelay = time.Second
+	start := time.Now()
+	output, exitCode, duration, err := captureCommand(c, out)
+	if err != nil {
+		return GateResult{}, fmt.Errorf("gate %s: %w", g.Key(), err)
+	}
+	result := GateResult{
+		ExitCode: exitCode,
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+		Duration: duration,
+		RanAt:    start.UTC(),
+	}
+	result.Passed = !result.TimedOut && exitCode == g.ExpectExit
+
+	prefix := "gate"
+	if workItemID != "" {
+		prefix = workItemID + "-gate"
+	}
+	name := fmt.Sprintf("%s-%s-%s.log", prefix, g.Key(), start.UTC().Format("20060102T150405Z"))
+	run := &EvidenceRun{Command: g.Command, ExitCode: exitCode, Duration: duration, Dir: "."}
+	stored, err := WriteEvidence(name, output, EvidenceEntry{Source: "gate:" + g.Key(), Run: run})
+	if err != nil {
+		return result, err
+	}
+	result.Evidence = stored.Path
+	if workItemID != "" {
+		if err := AttachEvidence(workItemID, stored.Path); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// updateGateHealth opens a health issue for a failing gate and resolves it once the gate passes.
+func updateGateHealth(h *HealthSnapshot, g QualityGate, r GateResult) {
+	source := "gate:" + g.Key()
+	for i := range h.Issues {
+		if h.Issues[i].Source != source || !h.Issues[i].Open() {
+			continue
+		}
+		if r.Passed {
+			resolved := r.RanAt
+			h.Issues[i].ResolvedAt = &resolved
+		}
+		return
+	}
+	if !r.Passed {
+		_, _ = h.AddHealthIssue(fmt.Sprintf("Quality gate %s is failing: %s", g.Key(), g.Label()), SeverityHigh, source, nil)
+	}
+}
+
+// gateHealthStatus is failing when any executable gate's last run failed. Once gates pass, a
+// status of ok, failing or unknown is derived from the open issues; any other status, such as
+// degraded from ctx health set, is kept.
+func gateHealthStatus(h HealthSnapshot, gates []QualityGate, results map[string]GateResult) string {
+	for _, g := range gates {
+		if r, ok := results[g.Key()]; ok && g.Executable() && !r.Passed {
+			return "failing"
+		}
+	}
+	switch h.Status {
+	case "", "unknown", "ok", "failing":
+		return issueHealthStatus(h)
+	}
+	return h.Status
+}
+
+// qualityGateLines renders gates for prompts, with the last result of executable gates.
+func qualityGateLines(gates []QualityGate, results map[string]GateResult) []string {
+	lines := make([]string, 0, len(gates))
+	for _, g := range gates {
+		line := g.Label()
+		if g.Executable() {
+			if r, ok := results[g.Key()]; ok {
+				line += " [" + r.Status() + "]"
+			} else {
+				line += " [not run]"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Health issue severities, lowest first.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3, SeverityCritical: 4}
+
+// HealthIssue is one tracked operational problem.
+type HealthIssue struct {
+	ID         string     `yaml:"id"`
+	Summary    string     `yaml:"summary"`
+	Severity   string     `yaml:"severity"`
+	Source     string     `yaml:"source,omitempty"`
+	Since      time.Time  `yaml:"since,omitempty"`
+	ResolvedAt *time.Time `yaml:"resolved_at,omitempty"`
+	WorkItems  []string   `yaml:"work_items,omitempty"`
+}
+
+// UnmarshalYAML also accepts the legacy plain-string form, read as an open medium issue.
+func (h *HealthIssue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		h.Summary = node.Value
+		h.Severity = SeverityMedium
+		return nil
+	}
+	type plain HealthIssue
+	return node.Decode((*plain)(h))
+}
+
+// Open reports whether the issue is unresolved.
+func (h HealthIssue) Open() bool {
+	return h.ResolvedAt == nil
+}
+
+// String renders the issue as one line for prompts and ctx health show.
+func (h HealthIssue) String() string {
+	var details []string
+	if h.Source != "" {
+		details = append(details, "source: "+h.Source)
+	}
+	if !h.Since.IsZero() {
+		details = append(details, "since "+h.Since.Format("2006-01-02"))
+	}
+	if len(h.WorkItems) > 0 {
+		details = append(details, strings.Join(h.WorkItems, ", "))
+	}
+	line := fmt.Sprintf("[%s] %s", h.Severity, h.Summary)
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, "; ") + ")"
+	}
+	return line
+}
+
+// ValidateSeverity accepts low, medium, high or critical.
+func ValidateSeverity(s string) error {
+	if _, ok := severityRank[s]; !ok {
+		return fmt.Errorf("unknown severity %q (known: low, medium, high, critical)", s)
+	}
+	return nil
+}
+
+// normalizeHealth gives legacy issues IDs and a severity.
+func normalizeHealth(h *HealthSnapshot) {
+	for i := range h.Issues {
+		if h.Issues[i].Severity == "" {
+			h.Issues[i].Severity = SeverityMedium
+		}
+		if h.Issues[i].ID == "" {
+			h.Issues[i].ID = nextHealthIssueID(h.Issues)
+		}
+	}
+}
+
+func nextHealthIssueID(issues []HealthIssue) string {
+	highest := 0
+	for _, issue := range issues {
+		var n int
+		if _, err := fmt.Sscanf(issue.ID, "H-%d", &n); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return fmt.Sprintf("H-%d", highest+1)
+}
+
+// AddHealthIssue opens a new issue on the snapshot.
+func (h *HealthSnapshot) AddHealthIssue(summary, severity, source string, workItems []string) (HealthIssue, error) {
+	if strings.TrimSpace(summary) == "" {
+		return HealthIssue{}, fmt.Errorf("health issue summary is empty")
+	}
+	if severity == "" {
+		severity = SeverityMedium
+	}
+	if err := ValidateSeverity(severity); err != nil {
+		return HealthIssue{}, err
+	}
+	issue := HealthIssue{
+		ID:        nextHealthIssueID(h.Issues),
+		Summary:   strings.TrimSpace(summary),
+		Severity:  severity,
+		Source:    source,
+		Since:     time.Now().UTC(),
+		WorkItems: workItems,
+	}
+	h.Issues = append(h.Issues, issue)
+	return issue, nil
+}
+
+// ResolveHealthIssue marks an open issue resolved.
+func (h *HealthSnapshot) ResolveHealthIssue(id string) (HealthIssue, error) {
+	for i := range h.Issues {
+		if !strings.EqualFold(h.Issues[i].ID, id) {
+			continue
+		}
+		if !h.Issues[i].Open() {
+			return h.Issues[i], fmt.Errorf("health issue %s is already resolved", h.Issues[i].ID)
+		}
+		now := time.Now().UTC()
+		h.Issues[i].ResolvedAt = &now
+		return h.Issues[i], nil
+	}
+	return HealthIssue{}, fmt.Errorf("health issue %q not found", id)
+}
+
+// OpenIssues returns unresolved issues at or above minSeverity ("" means all), most severe first.
+func (h HealthSnapshot) OpenIssues(minSeverity string) []HealthIssue {
+	floor := severityRank[minSeverity]
+	var out []HealthIssue
+	for _, issue := range h.Issues {
+		if issue.Open() && severityRank[issue.Severity] >= floor {
+			out = append(out, issue)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return severityRank[out[i].Severity] > severityRank[out[j].Severity]
+	})
+	return out
+}
+
+// issueHealthStatus derives a status from open issues: failing for high or critical,
+// degraded for anything less severe, ok when none are open.
+func issueHealthStatus(h HealthSnapshot) string {
+	open := h.OpenIssues("")
+	switch {
+	case len(open) == 0:
+		return "ok"
+	case severityRank[open[0].Severity] >= severityRank[SeverityHigh]:
+		return "failing"
+	}
+	return "degraded"
+}
+
+// healthIssueLines renders the open issues a profile shows.
+func healthIssueLines(h HealthSnapshot, minSeverity string) []string {
+	var lines []string
+	for _, issue := range h.OpenIssues(minSeverity) {
+		lines = append(lines, issue.String())
+	}
+	return lines
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// inTempRepo runs the test from an empty directory with an .agent layout for the default template.
+func inTempRepo(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := EnsureAgentLayout("default"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestEvidence stores data under .agent as is, bypassing redaction, and indexes it.
+func writeTestEvidence(rel string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(AgentPath(rel)), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(AgentPath(rel), data, 0o644); err != nil {
+		return err
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return err
+	}
+	idx.Entries = append(idx.Entries, EvidenceEntry{Path: rel, SHA256: contentSHA256(data), Size: int64(len(data))})
+	return SaveEvidenceIndex(idx)
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+var atxHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// markdownHeading is an ATX heading found outside fenced code blocks.
+type markdownHeading struct {
+	Line  int
+	Level int
+	Title string
+}
+
+// markdownHeadings scans body for headings, skipping ``` and ~~~ fenced blocks.
+func markdownHeadings(lines []string) []markdownHeading {
+	var headings []markdownHeading
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			headings = append(headings, markdownHeading{Line: i, Level: len(m[1]), Title: m[2]})
+		}
+	}
+	return headings
+}
+
+// selectMarkdownSections returns the named sections of body (heading included), in document
+// order. A section runs until the next heading of the same or a higher level, so nested
+// subsections come along. Names match heading text case-insensitively; missing names are returned.
+func selectMarkdownSections(body string, names []string) (string, []string) {
+	lines := strings.Split(body, "\n")
+	headings := markdownHeadings(lines)
+	wanted := map[string]bool{}
+	for _, n := range names {
+		wanted[strings.ToLower(strings.TrimSpace(n))] = true
+	}
+	found := map[string]bool{}
+
+	var parts []string
+	end := -1
+	for i, h := range headings {
+		key := strings.ToLower(h.Title)
+		if !wanted[key] {
+			continue
+		}
+		found[key] = true
+		if h.Line < end {
+			// Already included with the enclosing section.
+			continue
+		}
+		end = len(lines)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line
+				break
+			}
+		}
+		parts = append(parts, strings.TrimSpace(strings.Join(lines[h.Line:end], "\n")))
+	}
+
+	var missing []string
+	for _, n := range names {
+		if !found[strings.ToLower(strings.TrimSpace(n))] {
+			missing = append(missing, n)
+		}
+	}
+	return strings.Join(parts, "\n\n"), missing
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+const markdownFixture = `Intro text.
+
+## Notes
+The size header is parsed with Atoi.
+
+### Details
+Atoi returns int.
+
+` + "```sh" + `
+## Not a heading
+` + "```" + `
+
+## Repro
+curl -T big.bin localhost:8080/upload
+
+~~~
+# also not a heading
+~~~
+
+# Appendix
+## notes
+Lowercase duplicate.
+`
+
+func TestSelectMarkdownSections(t *testing.T) {
+	cases := []struct {
+		name        string
+		names       []string
+		want        string
+		wantMissing []string
+	}{
+		{
+			name:  "nested subsection comes along",
+			names: []string{"Notes"},
+			want:  "## Notes\nThe size header is parsed with Atoi.\n\n### Details\nAtoi returns int.\n\n```sh\n## Not a heading\n```\n\n## notes\nLowercase duplicate.",
+		},
+		{
+			name:        "headings in fences are ignored",
+			names:       []string{"Not a heading", "also not a heading"},
+			wantMissing: []string{"Not a heading", "also not a heading"},
+		},
+		{
+			name:  "case-insensitive and trimmed",
+			names: []string{"  REPRO "},
+			want:  "## Repro\ncurl -T big.bin localhost:8080/upload\n\n~~~\n# also not a heading\n~~~",
+		},
+		{
+			name:  "document order, not request order",
+			names: []string{"Repro", "Details"},
+			want:  "### Details\nAtoi returns int.\n\n```sh\n## Not a heading\n```\n\n## Repro\ncurl -T big.bin localhost:8080/upload\n\n~~~\n# also not a heading\n~~~",
+		},
+		{
+			// Details starts before Notes ends (h.Line < end), so it is not repeated but still counts as found.
+			name:  "overlapping subsection is not repeated",
+			names: []string{"Notes", "Details"},
+			want:  "## Notes\nThe size header is parsed with Atoi.\n\n### Details\nAtoi returns int.\n\n```sh\n## Not a heading\n```\n\n## notes\nLowercase duplicate.",
+		},
+		{
+			name:  "parent section runs to the end",
+			names: []string{"Appendix"},
+			want:  "# Appendix\n## notes\nLowercase duplicate.",
+		},
+		{
+			name:        "missing names are reported as given",
+			names:       []string{"Repro", "Logs"},
+			want:        "## Repro\ncurl -T big.bin localhost:8080/upload\n\n~~~\n# also not a heading\n~~~",
+			wantMissing: []string{"Logs"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, missing := selectMarkdownSections(markdownFixture, tc.names)
+			if got != tc.want {
+				t.Errorf("sections:\n%s\nwant:\n%s", got, tc.want)
+			}
+			if strings.Join(missing, "|") != strings.Join(tc.wantMissing, "|") {
+				t.Errorf("missing = %q, want %q", missing, tc.wantMissing)
+			}
+		})
+	}
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	lines := strings.Split("# Title #\n####### too deep\n#no space\n  ## Indented\n```\n# fenced\n```\n### Closed ###", "\n")
+	var got []string
+	for _, h := range markdownHeadings(lines) {
+		got = append(got, strings.Repeat("#", h.Level)+" "+h.Title)
+	}
+	want := []string{"# Title", "### Closed"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("headings = %q, want %q", got, want)
+	}
+}
@@ -11,37 +11,74 @@ type Architecture struct {
 
 // HealthSnapshot captures lightweight operational health.
 type HealthSnapshot struct {
-	Status string   `yaml:"status,omitempty"`
-	Issues []string `yaml:"issues,omitempty"`
+	Status string        `yaml:"status,omitempty"`
+	Issues []HealthIssue `yaml:"issues,omitempty"`
 }
 
 // Context represents slow-changing project context shared across work items.
 type Context struct {
+	// Extends lists templates this one is layered on, in order; only templates use it.
+	Extends []string `yaml:"extends,omitempty"`
 	Project struct {
 		Name     string `yaml:"name"`
 		Summary  string `yaml:"summary"`
 		Template string `yaml:"template,omitempty"`
 	} `yaml:"project"`
-	Architecture Architecture       `yaml:"architecture"`
-	Standards     map[string][]string `yaml:"standards,omitempty"`
-	Constraints   []string            `yaml:"constraints,omitempty"`
-	QualityGates  []string            `yaml:"quality_gates,omitempty"`
+	Architecture Architecture        `yaml:"architecture"`
+	Standards    map[string][]string `yaml:"standards,omitempty"`
+	// StandardsByIntent maps work item intents to the standards scopes prompts include;
+	// the "shared" scope is always included.
+	StandardsByIntent map[string][]string `yaml:"standards_by_intent,omitempty"`
+	Constraints       []string            `yaml:"constraints,omitempty"`
+	QualityGates      []QualityGate       `yaml:"quality_gates,omitempty"`
 }
 
 // State represents fast-changing state that is easy to resume.
 type State struct {
-	ActiveWorkItem   string `yaml:"active_work_item"`
-	LastSummary      string `yaml:"last_summary,omitempty"`
-	BranchSuggestion string `yaml:"branch_suggestion,omitempty"`
+	ActiveWorkItem   string         `yaml:"active_work_item"`
+	LastSummary      string         `yaml:"last_summary,omitempty"`
+	BranchSuggestion string         `yaml:"branch_suggestion,omitempty"`
 	Health           HealthSnapshot `yaml:"health,omitempty"`
+	// Gates holds the last result of each executable quality gate, by gate key.
+	Gates map[string]GateResult `yaml:"gates,omitempty"`
 }
 
 // PromptProfile controls how much context is expanded when building a prompt.
 type PromptProfile struct {
-	Description         string `yaml:"description"`
+	Description string `yaml:"description"`
+	// Extends names a parent profile whose settings this one inherits and overrides.
+	Extends             string `yaml:"extends,omitempty"`
 	IncludeArchitecture bool   `yaml:"include_architecture"`
 	IncludeStandards    bool   `yaml:"include_standards"`
-	Detail              string `yaml:"detail,omitempty"`
+	// AllStandards includes every standards scope instead of those mapped to the work item's intents.
+	AllStandards bool   `yaml:"all_standards,omitempty"`
+	Detail       string `yaml:"detail,omitempty"`
+	// Template names .agent/prompts/<template>.tmpl; its {{define}} blocks override built-in sections.
+	Template string `yaml:"template,omitempty"`
+	// Format is the default output format (md, xml, json, txt or chat-json).
+	Format string `yaml:"format,omitempty"`
+	// Model is the model name written into chat-json request bodies.
+	Model string `yaml:"model,omitempty"`
+	// MaxTokens caps the estimated prompt size; zero means unlimited.
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+	// Tokenizer names a built-in tokenizer or a vocabulary under .agent/tokenizers.
+	Tokenizer string `yaml:"tokenizer,omitempty"`
+	// TrimOrder lists sections to summarize, then drop, until the prompt fits MaxTokens.
+	TrimOrder []string `yaml:"trim_order,omitempty"`
+	// BodySections limits the body section to these markdown headings (e.g. Notes, Repro);
+	// setting it also includes the body section.
+	BodySections []string `yaml:"body_sections,omitempty"`
+	// EvidenceExcerpts opts evidence into head/tail/grep excerpts; empty keeps evidence paths-only.
+	EvidenceExcerpts []ExcerptPolicy `yaml:"evidence_excerpts,omitempty"`
+	// ExcerptsMaxBytes caps all excerpts together; zero means 8000.
+	ExcerptsMaxBytes int `yaml:"excerpts_max_bytes,omitempty"`
+	// HealthMinSeverity hides open health issues below this severity (low, medium, high, critical).
+	HealthMinSeverity string `yaml:"health_min_severity,omitempty"`
+	// Sections includes, excludes, or reorders individual prompt sections.
+	Sections map[string]SectionSetting `yaml:"sections,omitempty"`
+
+	// set records the keys present in YAML, so extends can tell an explicit false or 0 from unset.
+	set map[string]bool
 }
 
 // PromptProfileSet wraps configured profiles.
@@ -51,15 +88,27 @@ type PromptProfileSet struct {
 
 // WorkItem metadata is stored in front matter, while Body preserves user edits.
 type WorkItem struct {
-	ID                  string    `yaml:"id"`
-	Title               string    `yaml:"title"`
-	Intent              []string  `yaml:"intent,omitempty"`
-	Status              string    `yaml:"status"`
-	CreatedAt           time.Time `yaml:"created_at"`
-	Evidence            []string  `yaml:"evidence,omitempty"`
-	LastSummary         string    `yaml:"last_summary,omitempty"`
-	AcceptanceCriteria  []string  `yaml:"acceptance_criteria,omitempty"`
-	BranchSuggestion    string    `yaml:"branch_suggestion,omitempty"`
+	ID                 string        `yaml:"id"`
+	Title              string        `yaml:"title"`
+	Intent             []string      `yaml:"intent,omitempty"`
+	Status             string        `yaml:"status"`
+	CreatedAt          time.Time     `yaml:"created_at"`
+	Evidence           []string      `yaml:"evidence,omitempty"`
+	LastSummary        string        `yaml:"last_summary,omitempty"`
+	AcceptanceCriteria []string      `yaml:"acceptance_criteria,omitempty"`
+	BranchSuggestion   string        `yaml:"branch_suggestion,omitempty"`
+	Sessions           []WorkSession `yaml:"sessions,omitempty"`
+	// DoneAt is set by ctx work done.
+	DoneAt *time.Time `yaml:"done_at,omitempty"`
+	// DoneOverride records why the item was forced done past failing checks.
+	DoneOverride *DoneOverride `yaml:"done_override,omitempty"`
+}
+
+// WorkSession records one start/stop cycle on a work item.
+type WorkSession struct {
+	StartedAt time.Time  `yaml:"started_at"`
+	StoppedAt *time.Time `yaml:"stopped_at,omitempty"`
+	Summary   string     `yaml:"summary,omitempty"`
 }
 
 // WorkItemFile combines metadata with free-form body text.
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+)
+
+const policyFile = "policy.yaml"
+
+// Quota actions for EvidencePolicy.OnQuota.
+const (
+	QuotaWarn   = "warn"
+	QuotaRefuse = "refuse"
+)
+
+// Policy is read from .agent/policy.yaml.
+type Policy struct {
+	Evidence EvidencePolicy `yaml:"evidence,omitempty"`
+	Done     DonePolicy     `yaml:"done,omitempty"`
+}
+
+// EvidencePolicy bounds what evidence may add to the repo. Zero values disable a limit.
+type EvidencePolicy struct {
+	// MaxFileBytes and MaxTotalBytes cap stored (possibly compressed) sizes.
+	MaxFileBytes  int64 `yaml:"max_file_bytes,omitempty"`
+	MaxTotalBytes int64 `yaml:"max_total_bytes,omitempty"`
+	// CompressOverBytes gzips text evidence larger than this.
+	CompressOverBytes int64 `yaml:"compress_over_bytes,omitempty"`
+	// ExpireDoneAfterDays lets prune delete evidence linked only to items done this long ago.
+	ExpireDoneAfterDays int `yaml:"expire_done_after_days,omitempty"`
+	// OnQuota is warn (default) or refuse.
+	OnQuota string `yaml:"on_quota,omitempty"`
+}
+
+// LoadPolicy reads .agent/policy.yaml; a missing file means no limits.
+func LoadPolicy() (Policy, error) {
+	var p Policy
+	if err := readYAML(AgentPath(policyFile), &p); err != nil && !os.IsNotExist(err) {
+		return p, fmt.Errorf("%s: %w", AgentPath(policyFile), err)
+	}
+	if p.Evidence.OnQuota == "" {
+		p.Evidence.OnQuota = QuotaWarn
+	}
+	if p.Evidence.OnQuota != QuotaWarn && p.Evidence.OnQuota != QuotaRefuse {
+		return p, fmt.Errorf("%s: evidence.on_quota must be warn or refuse, got %q", AgentPath(policyFile), p.Evidence.OnQuota)
+	}
+	return p, nil
+}
@@ -23,113 +23,421 @@ type PromptData struct {
 	TaskAcceptance []string
 	HealthStatus   string
 	HealthIssues   []string
+	// Detail is the profile's detail level: summary, balanced or full.
+	Detail string
+	// Body is the work item's markdown body.
+	Body string
+	// Sessions lists past work sessions, oldest first.
+	Sessions []string
+	// EvidenceExcerpts are set only when the profile opts in with evidence_excerpts.
+	EvidenceExcerpts []EvidenceExcerpt
+	// ExcerptsOmitted counts excerpts left out by the profile's excerpts_max_bytes.
+	ExcerptsOmitted int
+	// FailingTests lists failures from test reports in the work item's evidence.
+	FailingTests []string
+	// Standards holds the standards scopes relevant to the work item's intents.
+	Standards map[string][]string
 }
 
-var promptTemplate = `Task: {{.WorkItem.Title}} ({{.WorkItem.ID}})
-Intent: {{join .WorkItem.Intent ", "}}
-Status: {{.WorkItem.Status}}
-Health: {{healthLine .HealthStatus}}
-Last Summary: {{summaryLine .State.LastSummary .WorkItem.LastSummary}}
+// Detail levels for PromptProfile.Detail.
+const (
+	DetailSummary  = "summary"
+	DetailBalanced = "balanced"
+	DetailFull     = "full"
+)
+
+// summaryListItems is how many list items the summary detail level keeps.
+const summaryListItems = 3
+
+// maxFailingTests caps the failing tests section at other detail levels.
+const maxFailingTests = 10
 
-Constraints:
-{{bulletList .Constraints}}
+// promptSections lists prompt sections in their default render order.
+var promptSections = []string{
+	"task",
+	"body",
+	"constraints",
+	"quality_gates",
+	"evidence",
+	"evidence_excerpts",
+	"failing_tests",
+	"likely_files",
+	"acceptance",
+	"sessions",
+	"project",
+	"architecture",
+	"standards",
+	"health",
+}
 
-Quality Gates:
-{{bulletList .QualityGates}}
+// fullDetailSections render by default only at the full detail level.
+var fullDetailSections = map[string]bool{"body": true, "sessions": true}
 
-Evidence (paths only):
-{{bulletList .Evidence}}
+// defaultTrimOrder is used when a profile sets max_tokens without trim_order.
+var defaultTrimOrder = []string{"health", "standards", "evidence", "likely_files", "architecture", "project"}
 
-Likely Files:
-{{bulletList .LikelyFiles}}
+// trimmedListItems is how many bullets a summarized section keeps.
+const trimmedListItems = 3
 
-Task Acceptance:
-{{bulletList .TaskAcceptance}}
+// promptTemplate defines one named block per section; empty blocks are skipped.
+var promptTemplate = `{{define "task"}}Task: {{.WorkItem.Title}} ({{.WorkItem.ID}})
+Intent: {{join .WorkItem.Intent ", "}}
+Status: {{.WorkItem.Status}}
+Health: {{healthLine .HealthStatus}}
+Last Summary: {{summaryLine .State.LastSummary .WorkItem.LastSummary}}{{end}}
+{{define "constraints"}}Constraints:
+{{bulletList .Constraints}}{{end}}
+{{define "quality_gates"}}Quality Gates:
+{{bulletList .QualityGates}}{{end}}
+{{define "evidence"}}Evidence (paths only):
+{{bulletList .Evidence}}{{end}}
+{{define "evidence_excerpts"}}{{if .EvidenceExcerpts}}Evidence Excerpts:{{range .EvidenceExcerpts}}
 
-{{if .Context.Project.Summary}}Project Context:
-- {{.Context.Project.Summary}}
-{{end}}{{if includeArch .Profile}}
-Architecture:
-- {{archSummary .Context.Architecture}}{{end}}{{if includeStandards .Profile}}
-Standards:
-{{scopedList .Context.Standards}}{{end}}{{if healthIssuesPresent .HealthIssues}}
+{{.Path}} ({{.Label}}):
+{{.Fence}}
+{{.Text}}
+{{.Fence}}{{end}}{{if .ExcerptsOmitted}}
 
-Health Issues:
-{{bulletList .HealthIssues}}{{end}}
+({{.ExcerptsOmitted}} more excerpt(s) left out by excerpts_max_bytes; see the evidence paths){{end}}{{end}}{{end}}
+{{define "failing_tests"}}{{if .FailingTests}}Failing Tests:
+{{bulletList .FailingTests}}{{end}}{{end}}
+{{define "likely_files"}}Likely Files:
+{{bulletList .LikelyFiles}}{{end}}
+{{define "body"}}{{if .Body}}Work Item Notes:
+{{.Body}}{{end}}{{end}}
+{{define "acceptance"}}Task Acceptance:
+{{bulletList .TaskAcceptance}}{{end}}
+{{define "sessions"}}{{if .Sessions}}Session History:
+{{bulletList .Sessions}}{{end}}{{end}}
+{{define "project"}}{{if .Context.Project.Summary}}Project Context:
+- {{.Context.Project.Summary}}{{end}}{{end}}
+{{define "architecture"}}Architecture:
+- {{archSummary .Context.Architecture}}{{end}}
+{{define "standards"}}Standards:
+{{if eq .Detail "summary"}}- Scopes: {{join (scopeNames .Standards) ", "}}{{else if eq .Detail "full"}}{{fullScopedList .Standards}}{{else}}{{scopedList .Standards}}{{end}}{{end}}
+{{define "health"}}{{if healthIssuesPresent .HealthIssues}}Health Issues:
+{{bulletList .HealthIssues}}{{end}}{{end}}
 `
 
-// BuildPrompt assembles the prompt and writes exports/current.prompt.md.
-func BuildPrompt(profileName string) (string, error) {
+// PromptSection is one rendered block of a prompt.
+type PromptSection struct {
+	Name string
+	// Title is the built-in heading of the section; Items are its list entries, if it is a list.
+	// Structured formats encode them instead of parsing Text.
+	Title  string
+	Items  []string
+	Text   string
+	Tokens int
+}
+
+// PromptOptions selects how a prompt is built; empty fields fall back to profile defaults.
+type PromptOptions struct {
+	Profile string
+	Format  string
+	// Model is sent in chat-json request bodies.
+	Model string
+	// Out overrides the export path; Stdout skips writing files entirely.
+	Out    string
+	Stdout bool
+}
+
+// PromptResult describes a generated prompt and how it was fitted to its budget.
+type PromptResult struct {
+	Path string
+	// HistoryPath is the copy kept under exports/history/<WI>/.
+	HistoryPath string
+	Profile     string
+	WorkItem    string
+	Format      string
+	Tokenizer   string
+	// Output is the encoded prompt as written.
+	Output    string
+	Sections  []PromptSection
+	Tokens    int
+	MaxTokens int
+	// Trimmed records sections that were summarized or dropped to fit MaxTokens.
+	Trimmed []string
+}
+
+// BuildPrompt assembles the prompt and writes it per opts (see writePromptExport).
+func BuildPrompt(opts PromptOptions) (PromptResult, error) {
+	profileName := opts.Profile
 	if profileName == "" {
 		profileName = "cheap"
 	}
 	profiles, err := LoadPromptProfiles()
 	if err != nil {
-		return "", err
+		return PromptResult{}, err
+	}
+	profile, err := ResolvePromptProfile(profiles, profileName)
+	if err != nil {
+		return PromptResult{}, err
+	}
+	format := opts.Format
+	if format == "" {
+		format = profile.Format
+	}
+	if format == "" {
+		format = FormatMarkdown
 	}
-	_, ok := profiles.Profiles[profileName]
-	if !ok {
-		return "", fmt.Errorf("prompt profile %q not found", profileName)
+	if err := validatePromptFormat(format); err != nil {
+		return PromptResult{}, err
 	}
 
 	state, err := LoadState()
 	if err != nil {
-		return "", err
+		return PromptResult{}, err
 	}
 	if state.ActiveWorkItem == "" {
-		return "", fmt.Errorf("no active work item; start one with ctx work start <WI-XXX>")
+		return PromptResult{}, fmt.Errorf("no active work item; start one with ctx work start <WI-XXX>")
 	}
 
 	wiFile, err := LoadWorkItem(state.ActiveWorkItem)
 	if err != nil {
-		return "", err
+		return PromptResult{}, err
 	}
 	context, err := LoadContext()
 	if err != nil {
+		return PromptResult{}, err
+	}
+
+	tok, err := LoadTokenizer(profile.Tokenizer)
+	if err != nil {
+		return PromptResult{}, err
+	}
+	index, err := LoadEvidenceIndex()
+	if err != nil {
+		return PromptResult{}, err
+	}
+	data := newPromptData(profileName, profile, state, wiFile, context, index)
+	data.EvidenceExcerpts, data.ExcerptsOmitted = evidenceExcerpts(wiFile.Meta.Evidence, profile.EvidenceExcerpts, profile.ExcerptsMaxBytes, tok)
+	tpl, templatePath, err := loadPromptTemplate(profile)
+	if err != nil {
+		return PromptResult{}, err
+	}
+
+	sections, err := renderSections(tpl, data, profile, tok)
+	if err != nil {
+		return PromptResult{}, err
+	}
+	result := PromptResult{
+		Profile:   profileName,
+		WorkItem:  wiFile.Meta.ID,
+		Format:    format,
+		Tokenizer: tok.Name(),
+		MaxTokens: profile.MaxTokens,
+	}
+	result.Sections, result.Trimmed = fitTokenBudget(sections, trimOrder(profile), profile.MaxTokens, tok)
+	model := opts.Model
+	if model == "" {
+		model = profile.Model
+	}
+	result.Output, err = encodePrompt(format, data, result.Sections, model)
+	if err != nil {
+		return PromptResult{}, err
+	}
+	result.Tokens = tok.Count(result.Output)
+
+	sources := []string{
+		AgentPath(contextFile),
+		AgentPath(stateFile),
+		AgentPath(promptProfilesFile),
+		WorkItemPath(wiFile.Meta.ID),
+	}
+	if templatePath != "" {
+		sources = append(sources, templatePath)
+	}
+	sources = append(sources, AgentPath(evidenceDir, evidenceIndexFile))
+	for _, rel := range evidenceSources(wiFile.Meta.Evidence, data.EvidenceExcerpts, index) {
+		sources = append(sources, AgentPath(filepath.FromSlash(rel)))
+	}
+	if result.HistoryPath, err = recordPromptHistory(result, sources); err != nil {
+		return PromptResult{}, err
+	}
+	if opts.Stdout {
+		return result, nil
+	}
+	if result.Path, err = writePromptExport(result, opts.Out); err != nil {
+		return PromptResult{}, err
+	}
+	return result, nil
+}
+
+// evidenceSources lists the evidence files whose content reached the prompt: excerpted files and
+// files that contributed failing tests or stack frames.
+func evidenceSources(evidence []string, excerpts []EvidenceExcerpt, index EvidenceIndex) []string {
+	excerpted := map[string]bool{}
+	for _, e := range excerpts {
+		excerpted[e.Path] = true
+	}
+	var out []string
+	for _, rel := range evidence {
+		entry := index.Find(rel)
+		used := excerpted[rel] || entry != nil && (len(entry.Frames) > 0 || entry.Tests != nil && len(entry.Tests.Failures) > 0)
+		if used && !containsString(out, rel) {
+			out = append(out, rel)
+		}
+	}
+	return out
+}
+
+// writePromptExport writes to out when set; otherwise to exports/<WI>.<profile>.prompt.<ext>,
+// refreshing exports/current.prompt.<ext> as a copy for tools that read the fixed name.
+func writePromptExport(result PromptResult, out string) (string, error) {
+	if out != "" {
+		if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+			return "", err
+		}
+		return out, os.WriteFile(out, []byte(result.Output), 0o644)
+	}
+	current, err := TouchPromptFile(result.Format)
+	if err != nil {
+		return "", err
+	}
+	dest := PromptExportPath(result.WorkItem, result.Profile, result.Format)
+	if err := os.WriteFile(dest, []byte(result.Output), 0o644); err != nil {
 		return "", err
 	}
+	if err := os.WriteFile(current, []byte(result.Output), 0o644); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
 
+// newPromptData derives the template data for a work item from state, context and the evidence index.
+// At the summary detail level, lists other than task acceptance keep only their top items.
+func newPromptData(profileName string, profile PromptProfile, state State, wiFile *WorkItemFile, context Context, index EvidenceIndex) PromptData {
+	wi := wiFile.Meta
+	evidenceRule := "Do not embed logs; reference evidence paths."
+	if len(profile.EvidenceExcerpts) > 0 {
+		evidenceRule = "Evidence excerpts are partial; reference evidence paths for full logs."
+	}
 	constraints := mergeUnique(context.Constraints, []string{
 		"No network access; offline-only CLI.",
-		"Do not embed logs; reference evidence paths.",
+		evidenceRule,
 		"Keep prompts token-cheap; expand only by profile.",
 	})
-	taskAcceptance := wiFile.Meta.AcceptanceCriteria
+	taskAcceptance := wi.AcceptanceCriteria
 	if len(taskAcceptance) == 0 {
 		taskAcceptance = []string{"Work item completes without expanding scope."}
 	}
-	qualityGates := context.QualityGates
+	qualityGates := qualityGateLines(context.QualityGates, state.Gates)
 	if len(qualityGates) == 0 {
 		qualityGates = []string{"All tests pass.", "No breaking API changes."}
 	}
 
 	data := PromptData{
 		Profile:        profileName,
-		WorkItem:       wiFile.Meta,
+		WorkItem:       wi,
 		State:          state,
 		Context:        context,
 		Constraints:    constraints,
-		LikelyFiles:    likelyFiles(wiFile.Meta),
-		Evidence:       evidenceList(wiFile.Meta),
+		LikelyFiles:    likelyFiles(wi),
+		Evidence:       evidenceNoteList(evidenceList(wi), index.Notes()),
 		QualityGates:   qualityGates,
 		TaskAcceptance: taskAcceptance,
 		HealthStatus:   state.Health.Status,
-		HealthIssues:   state.Health.Issues,
+		HealthIssues:   healthIssueLines(state.Health, profile.HealthMinSeverity),
+		Detail:         detailLevel(profile),
+		Body:           promptBody(wiFile.Body, profile.BodySections),
+		Sessions:       sessionLines(wi.Sessions),
+		Standards:      context.Standards,
+	}
+	if !profile.AllStandards {
+		data.Standards = standardsForIntents(context.Standards, context.StandardsByIntent, wi.Intent)
+	}
+	// Stack frames and test failure locations lead Likely Files, ranked by frequency.
+	// Failure files are kept only when they resolve to a repo file, as stack frames are.
+	locations := evidenceFrames(wi.Evidence, index)
+	resolver := newRepoResolver()
+	for _, f := range failingTests(wi.Evidence, index) {
+		data.FailingTests = append(data.FailingTests, f.String())
+		if f.File == "" {
+			continue
+		}
+		if file, ok := resolver.resolve([]string{f.File}); ok {
+			locations = append(locations, StackFrame{File: file, Line: f.Line})
+		}
+	}
+	data.LikelyFiles = mergeUnique(rankedFrameFiles(locations), data.LikelyFiles)
+	data.FailingTests = topItems(data.FailingTests, maxFailingTests)
+	if data.Detail == DetailSummary {
+		data.FailingTests = topItems(data.FailingTests, summaryListItems)
+		data.LikelyFiles = topItems(data.LikelyFiles, summaryListItems)
+		data.Evidence = topItems(data.Evidence, summaryListItems)
+		data.QualityGates = topItems(data.QualityGates, summaryListItems)
+		data.HealthIssues = topItems(data.HealthIssues, summaryListItems)
 	}
+	return data
+}
+
+// promptBody returns the whole work item body, or only the named markdown sections.
+func promptBody(body string, sections []string) string {
+	if len(sections) == 0 {
+		return strings.TrimSpace(body)
+	}
+	selected, _ := selectMarkdownSections(body, sections)
+	return selected
+}
+
+func detailLevel(p PromptProfile) string {
+	if p.Detail == "" {
+		return DetailBalanced
+	}
+	return p.Detail
+}
+
+func validateDetail(detail string) error {
+	switch detail {
+	case "", DetailSummary, DetailBalanced, DetailFull:
+		return nil
+	}
+	return fmt.Errorf("unknown detail %q (known: %s, %s, %s)", detail, DetailSummary, DetailBalanced, DetailFull)
+}
 
-	tpl := template.Must(template.New("prompt").Funcs(template.FuncMap{
+// topItems keeps the first n items and notes how many were left out.
+func topItems(items []string, n int) []string {
+	if len(items) <= n {
+		return items
+	}
+	out := append([]string(nil), items[:n]...)
+	return append(out, fmt.Sprintf("... (%d more)", len(items)-n))
+}
+
+func sessionLines(sessions []WorkSession) []string {
+	var lines []string
+	for _, s := range sessions {
+		line := s.StartedAt.Format("2006-01-02 15:04")
+		if s.StoppedAt != nil {
+			line += " to " + s.StoppedAt.Format("2006-01-02 15:04")
+		} else {
+			line += " (ongoing)"
+		}
+		if strings.TrimSpace(s.Summary) != "" {
+			line += ": " + s.Summary
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// promptFuncs is the stable helper set available to every prompt template:
+//
+//	join(items, sep)            joins a list with sep
+//	bulletList(items)           renders "- item" lines, or "- None" when empty
+//	scopedList(map)             renders "- scope: a; b" lines sorted by scope
+//	fullScopedList(map)         renders each scope as a bullet with its rules nested below
+//	scopeNames(map)             returns the sorted scope names
+//	archSummary(architecture)   renders "style version — notes"
+//	summaryLine(parts...)       returns the first non-blank part, or "Not provided."
+//	healthLine(status)          returns status, or "unknown" when blank
+//	healthIssuesPresent(items)  reports whether any health issues are set
+func promptFuncs() template.FuncMap {
+	return template.FuncMap{
 		"join": func(items []string, sep string) string {
 			return strings.Join(items, sep)
 		},
 		"bulletList": bulletList,
-		"includeArch": func(profile string) bool {
-			p := profiles.Profiles[profile]
-			return p.IncludeArchitecture
-		},
-		"includeStandards": func(profile string) bool {
-			p := profiles.Profiles[profile]
-			return p.IncludeStandards
-		},
 		"summaryLine": func(parts ...string) string {
 			for _, p := range parts {
 				if strings.TrimSpace(p) != "" {
@@ -138,8 +446,10 @@ func BuildPrompt(profileName string) (string, error) {
 			}
 			return "Not provided."
 		},
-		"archSummary": archSummary,
-		"scopedList":  scopedList,
+		"archSummary":    archSummary,
+		"scopedList":     scopedList,
+		"fullScopedList": fullScopedList,
+		"scopeNames":     scopeNames,
 		"healthLine": func(status string) string {
 			if strings.TrimSpace(status) == "" {
 				return "unknown"
@@ -149,21 +459,194 @@ func BuildPrompt(profileName string) (string, error) {
 		"healthIssuesPresent": func(issues []string) bool {
 			return len(issues) > 0
 		},
-	}).Parse(promptTemplate))
+	}
+}
 
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, data); err != nil {
-		return "", err
+// renderSections executes the profile's planned section blocks in order, skipping empty output.
+func renderSections(tpl *template.Template, data PromptData, profile PromptProfile, tok Tokenizer) ([]PromptSection, error) {
+	var sections []PromptSection
+	for _, name := range sectionPlan(profile) {
+		var buf bytes.Buffer
+		if err := tpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, err
+		}
+		text := strings.TrimSpace(buf.String())
+		if text == "" {
+			continue
+		}
+		sections = append(sections, PromptSection{
+			Name:   name,
+			Title:  sectionTitles[name],
+			Items:  sectionItems(data, name),
+			Text:   text,
+			Tokens: tok.Count(text),
+		})
 	}
+	return sections, nil
+}
 
-	dest, err := TouchPromptFile()
-	if err != nil {
-		return "", err
+// sectionTitles are the headings the built-in layout gives each section.
+var sectionTitles = map[string]string{
+	"task":              "Task",
+	"body":              "Work Item Notes",
+	"constraints":       "Constraints",
+	"quality_gates":     "Quality Gates",
+	"evidence":          "Evidence (paths only)",
+	"evidence_excerpts": "Evidence Excerpts",
+	"failing_tests":     "Failing Tests",
+	"likely_files":      "Likely Files",
+	"acceptance":        "Task Acceptance",
+	"sessions":          "Session History",
+	"project":           "Project Context",
+	"architecture":      "Architecture",
+	"standards":         "Standards",
+	"health":            "Health Issues",
+}
+
+// sectionItems returns the list a section renders, or nil for sections that are not lists.
+func sectionItems(data PromptData, name string) []string {
+	var items []string
+	switch name {
+	case "constraints":
+		items = data.Constraints
+	case "quality_gates":
+		items = data.QualityGates
+	case "evidence":
+		items = data.Evidence
+	case "failing_tests":
+		items = data.FailingTests
+	case "likely_files":
+		items = data.LikelyFiles
+	case "acceptance":
+		items = data.TaskAcceptance
+	case "sessions":
+		items = data.Sessions
+	case "health":
+		items = data.HealthIssues
 	}
-	if err := os.WriteFile(dest, buf.Bytes(), 0o644); err != nil {
-		return "", err
+	var out []string
+	for _, item := range items {
+		if strings.TrimSpace(item) != "" {
+			out = append(out, item)
+		}
 	}
-	return dest, nil
+	return out
+}
+
+func joinSections(sections []PromptSection) string {
+	parts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		parts = append(parts, s.Text)
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// fitTokenBudget summarizes, then drops, sections in order until the prompt fits maxTokens.
+func fitTokenBudget(sections []PromptSection, order []string, maxTokens int, tok Tokenizer) ([]PromptSection, []string) {
+	if maxTokens <= 0 {
+		return sections, nil
+	}
+	fits := func() bool {
+		return tok.Count(joinSections(sections)) <= maxTokens
+	}
+	index := func(name string) int {
+		for i, s := range sections {
+			if s.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	summarized := map[string]bool{}
+	for _, name := range order {
+		if fits() {
+			break
+		}
+		i := index(name)
+		if i < 0 {
+			continue
+		}
+		text := summarizeSection(sections[i].Text, trimmedListItems)
+		if text == sections[i].Text {
+			continue
+		}
+		sections[i].Text = text
+		sections[i].Items = topItems(sections[i].Items, trimmedListItems)
+		sections[i].Tokens = tok.Count(text)
+		summarized[name] = true
+	}
+
+	dropped := map[string]bool{}
+	for _, name := range order {
+		if fits() {
+			break
+		}
+		i := index(name)
+		if i < 0 {
+			continue
+		}
+		sections = append(sections[:i], sections[i+1:]...)
+		dropped[name] = true
+	}
+
+	var trimmed []string
+	for _, name := range order {
+		switch {
+		case dropped[name]:
+			trimmed = append(trimmed, name+" (dropped)")
+		case summarized[name]:
+			trimmed = append(trimmed, name+" (summarized)")
+		}
+	}
+	return sections, trimmed
+}
+
+// summarizeSection keeps non-bullet lines and the first keep bullets, noting how many were cut.
+func summarizeSection(text string, keep int) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	bullets := 0
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "- ") {
+			out = append(out, line)
+			continue
+		}
+		bullets++
+		if bullets <= keep {
+			out = append(out, line)
+		}
+	}
+	if bullets <= keep {
+		return text
+	}
+	out = append(out, fmt.Sprintf("- ... (%d more)", bullets-keep))
+	return strings.Join(out, "\n")
+}
+
+func trimOrder(p PromptProfile) []string {
+	if len(p.TrimOrder) > 0 {
+		return p.TrimOrder
+	}
+	return defaultTrimOrder
+}
+
+func validateTrimOrder(order []string) error {
+	for _, name := range order {
+		if !isPromptSection(name) {
+			return fmt.Errorf("unknown section %q in trim_order (known: %s)", name, strings.Join(promptSections, ", "))
+		}
+	}
+	return nil
+}
+
+func isPromptSection(name string) bool {
+	for _, s := range promptSections {
+		if s == name {
+			return true
+		}
+	}
+	return false
 }
 
 func bulletList(items []string) string {
@@ -299,3 +782,59 @@ func scopedList(scopes map[string][]string) string {
 	}
 	return strings.TrimRight(b.String(), "\n")
 }
+
+// sharedStandardsScope is included whenever standards are selected by intent.
+const sharedStandardsScope = "shared"
+
+// standardsForIntents keeps the scopes mapped to the given intents plus "shared".
+// Without a mapping for any of the intents, every scope is kept.
+func standardsForIntents(scopes map[string][]string, byIntent map[string][]string, intents []string) map[string][]string {
+	wanted := map[string]bool{}
+	for _, intent := range intents {
+		for _, scope := range byIntent[intent] {
+			wanted[scope] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return scopes
+	}
+	wanted[sharedStandardsScope] = true
+	out := map[string][]string{}
+	for scope, items := range scopes {
+		if wanted[scope] {
+			out[scope] = items
+		}
+	}
+	return out
+}
+
+func scopeNames(scopes map[string][]string) []string {
+	keys := make([]string, 0, len(scopes))
+	for k := range scopes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fullScopedList(scopes map[string][]string) string {
+	if len(scopes) == 0 {
+		return "- None"
+	}
+	var b strings.Builder
+	for _, scope := range scopeNames(scopes) {
+		b.WriteString("- ")
+		b.WriteString(scope)
+		b.WriteString(":\n")
+		items := scopes[scope]
+		if len(items) == 0 {
+			b.WriteString("  - None\n")
+		}
+		for _, item := range items {
+			b.WriteString("  - ")
+			b.WriteString(item)
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prompt output formats. Every format encodes the same rendered sections.
+const (
+	FormatMarkdown = "md"
+	FormatXML      = "xml"
+	FormatJSON     = "json"
+	FormatText     = "txt"
+	// FormatChatJSON is an OpenAI-compatible chat completion request body.
+	FormatChatJSON = "chat-json"
+)
+
+// PromptFormats lists the supported --format values.
+func PromptFormats() []string {
+	return []string{FormatMarkdown, FormatXML, FormatJSON, FormatText, FormatChatJSON}
+}
+
+var (
+	mdEmphasis     = regexp.MustCompile(`(\*\*|__|` + "`" + `)`)
+	mdHeadingStart = regexp.MustCompile(`^#{1,6}\s+`)
+)
+
+// systemSections go into the chat system message; all others go into the user message.
+var systemSections = map[string]bool{
+	"constraints":   true,
+	"quality_gates": true,
+	"project":       true,
+	"architecture":  true,
+	"standards":     true,
+}
+
+// chatRequest is a chat completion request body accepted by OpenAI-compatible servers.
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// jsonPrompt is the JSON encoding of a prompt.
+type jsonPrompt struct {
+	Profile  string        `json:"profile"`
+	WorkItem string        `json:"work_item"`
+	Sections []jsonSection `json:"sections"`
+}
+
+type jsonSection struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title,omitempty"`
+	Content string   `json:"content"`
+	Items   []string `json:"items,omitempty"`
+}
+
+func validatePromptFormat(format string) error {
+	for _, f := range PromptFormats() {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown prompt format %q (known: %s)", format, strings.Join(PromptFormats(), ", "))
+}
+
+// encodePrompt renders sections in the requested format.
+func encodePrompt(format string, data PromptData, sections []PromptSection, model string) (string, error) {
+	switch format {
+	case "", FormatMarkdown:
+		return joinSections(sections), nil
+	case FormatXML:
+		return encodeXML(data, sections)
+	case FormatJSON:
+		out := jsonPrompt{Profile: data.Profile, WorkItem: data.WorkItem.ID}
+		for _, s := range sections {
+			out.Sections = append(out.Sections, jsonSection{
+				Name:    s.Name,
+				Title:   s.Title,
+				Content: sectionBody(s),
+				Items:   s.Items,
+			})
+		}
+		return encodeJSON(out)
+	case FormatChatJSON:
+		if strings.TrimSpace(model) == "" {
+			return "", fmt.Errorf("format %s needs a model: pass --model or set model: in the %q profile", FormatChatJSON, data.Profile)
+		}
+		var system, user []PromptSection
+		for _, s := range sections {
+			if systemSections[s.Name] {
+				system = append(system, s)
+			} else {
+				user = append(user, s)
+			}
+		}
+		req := chatRequest{Model: model}
+		if len(system) > 0 {
+			req.Messages = append(req.Messages, chatMessage{Role: "system", Content: strings.TrimSuffix(joinSections(system), "\n")})
+		}
+		req.Messages = append(req.Messages, chatMessage{Role: "user", Content: strings.TrimSuffix(joinSections(user), "\n")})
+		return encodeJSON(req)
+	case FormatText:
+		parts := make([]string, 0, len(sections))
+		for _, s := range sections {
+			parts = append(parts, plainText(s.Text))
+		}
+		return strings.Join(parts, "\n\n") + "\n", nil
+	}
+	return "", validatePromptFormat(format)
+}
+
+// encodeJSON indents v without HTML-escaping, since prompts are not embedded in HTML.
+func encodeJSON(v any) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// encodeXML writes one element per section, named after it. List sections hold an <item> per
+// entry; the others hold the section body as escaped text.
+func encodeXML(data PromptData, sections []PromptSection) (string, error) {
+	var b strings.Builder
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: "prompt"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "profile"}, Value: data.Profile},
+		{Name: xml.Name{Local: "work_item"}, Value: data.WorkItem.ID},
+	}}
+	tokens := []xml.Token{root}
+	for _, s := range sections {
+		start := xml.StartElement{Name: xml.Name{Local: s.Name}}
+		if s.Title != "" {
+			start.Attr = []xml.Attr{{Name: xml.Name{Local: "title"}, Value: s.Title}}
+		}
+		tokens = append(tokens, start)
+		if len(s.Items) > 0 {
+			for _, item := range s.Items {
+				tokens = append(tokens, xml.StartElement{Name: xml.Name{Local: "item"}}, xml.CharData(item), xml.EndElement{Name: xml.Name{Local: "item"}})
+			}
+		} else {
+			tokens = append(tokens, xml.CharData(sectionBody(s)))
+		}
+		tokens = append(tokens, start.End())
+	}
+	tokens = append(tokens, root.End())
+	for _, t := range tokens {
+		if err := enc.EncodeToken(t); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return "", err
+	}
+	return b.String() + "\n", nil
+}
+
+// sectionBody is the section text without its built-in heading line.
+func sectionBody(s PromptSection) string {
+	if s.Title != "" {
+		if body, ok := strings.CutPrefix(s.Text, s.Title+":\n"); ok {
+			return body
+		}
+	}
+	return s.Text
+}
+
+// plainText strips markdown headings, emphasis and code fences.
+func plainText(text string) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		if mdHeadingStart.MatchString(line) {
+			line = mdHeadingStart.ReplaceAllString(line, "")
+			if !strings.HasSuffix(line, ":") {
+				line += ":"
+			}
+		}
+		out = append(out, mdEmphasis.ReplaceAllString(line, ""))
+	}
+	return strings.Join(out, "\n")
+}
+
+// promptExtension maps a format to the file extension used for exports.
+func promptExtension(format string) string {
+	if format == FormatChatJSON {
+		return "chat.json"
+	}
+	return format
+}
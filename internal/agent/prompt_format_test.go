@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func formatFixture() (PromptData, []PromptSection) {
+	data := PromptData{Profile: "standard", WorkItem: WorkItem{ID: "WI-007"}}
+	sections := []PromptSection{
+		{Name: "task", Title: "Task", Text: "Task: Fix upload (WI-007)"},
+		{Name: "constraints", Title: "Constraints", Items: []string{"Keep the public API stable."}, Text: "Constraints:\n- Keep the public API stable."},
+	}
+	return data, sections
+}
+
+func TestEncodeChatJSONRequiresModel(t *testing.T) {
+	data, sections := formatFixture()
+	for _, model := range []string{"", "  "} {
+		_, err := encodePrompt(FormatChatJSON, data, sections, model)
+		if err == nil || !strings.Contains(err.Error(), "needs a model") {
+			t.Errorf("model %q: error = %v, want a missing model error", model, err)
+		}
+	}
+
+	out, err := encodePrompt(FormatChatJSON, data, sections, "llama3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req chatRequest
+	if err := json.Unmarshal([]byte(out), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Model != "llama3" || len(req.Messages) != 2 {
+		t.Fatalf("request = %+v", req)
+	}
+	if req.Messages[0].Role != "system" || !strings.Contains(req.Messages[0].Content, "Keep the public API stable.") {
+		t.Errorf("system message = %+v", req.Messages[0])
+	}
+	if req.Messages[1].Role != "user" || req.Messages[1].Content != "Task: Fix upload (WI-007)" {
+		t.Errorf("user message = %+v", req.Messages[1])
+	}
+}
+
+func TestEncodeXMLEscapesSectionData(t *testing.T) {
+	data, sections := formatFixture()
+	data.Profile = `a "quoted" <profile>`
+	sections = append(sections,
+		PromptSection{Name: "likely_files", Title: "Likely Files", Items: []string{"a<b>.go", `"quoted" & more`}, Text: "Likely Files:\n- a<b>.go\n- \"quoted\" & more"},
+		PromptSection{Name: "body", Title: "Work Item Notes", Text: "Work Item Notes:\nif a < b && c > d {\n\t</body>\n}"},
+	)
+	out, err := encodePrompt(FormatXML, data, sections, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "</body>\n}") || strings.Contains(out, "a<b>") {
+		t.Fatalf("xml is not escaped:\n%s", out)
+	}
+
+	var got struct {
+		Profile     string `xml:"profile,attr"`
+		WorkItem    string `xml:"work_item,attr"`
+		Constraints struct {
+			Title string   `xml:"title,attr"`
+			Items []string `xml:"item"`
+		} `xml:"constraints"`
+		LikelyFiles struct {
+			Items []string `xml:"item"`
+		} `xml:"likely_files"`
+		Body string `xml:"body"`
+	}
+	if err := xml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if got.Profile != data.Profile || got.WorkItem != "WI-007" {
+		t.Errorf("attrs = %q, %q", got.Profile, got.WorkItem)
+	}
+	if got.Constraints.Title != "Constraints" || strings.Join(got.Constraints.Items, "|") != "Keep the public API stable." {
+		t.Errorf("constraints = %+v", got.Constraints)
+	}
+	if strings.Join(got.LikelyFiles.Items, "|") != `a<b>.go|"quoted" & more` {
+		t.Errorf("likely files = %q", got.LikelyFiles.Items)
+	}
+	if got.Body != "if a < b && c > d {\n\t</body>\n}" {
+		t.Errorf("body = %q", got.Body)
+	}
+}
+
+func TestEncodeJSONUsesSectionData(t *testing.T) {
+	data, sections := formatFixture()
+	// A body line that looks like a heading must not be split off or parsed as a list.
+	sections = append(sections, PromptSection{Name: "body", Title: "Work Item Notes", Text: "Work Item Notes:\nSteps:\n- run it"})
+	out, err := encodePrompt(FormatJSON, data, sections, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got jsonPrompt
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []jsonSection{
+		{Name: "task", Title: "Task", Content: "Task: Fix upload (WI-007)"},
+		{Name: "constraints", Title: "Constraints", Content: "- Keep the public API stable.", Items: []string{"Keep the public API stable."}},
+		{Name: "body", Title: "Work Item Notes", Content: "Steps:\n- run it"},
+	}
+	if len(got.Sections) != len(want) {
+		t.Fatalf("sections = %+v", got.Sections)
+	}
+	for i, w := range want {
+		g := got.Sections[i]
+		if g.Name != w.Name || g.Title != w.Title || g.Content != w.Content || strings.Join(g.Items, "|") != strings.Join(w.Items, "|") {
+			t.Errorf("section %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	historyDir      = "history"
+	historyManifest = "manifest.yaml"
+)
+
+// PromptHistoryEntry records one generated prompt and the source files it was built from.
+type PromptHistoryEntry struct {
+	// File is relative to .agent.
+	File      string    `yaml:"file"`
+	Profile   string    `yaml:"profile"`
+	Format    string    `yaml:"format"`
+	CreatedAt time.Time `yaml:"created_at"`
+	// Sources maps .agent-relative paths to their sha256 at build time.
+	Sources map[string]string `yaml:"sources"`
+}
+
+// PromptManifest lists a work item's prompt history, oldest first.
+type PromptManifest struct {
+	WorkItem string               `yaml:"work_item"`
+	Entries  []PromptHistoryEntry `yaml:"entries"`
+}
+
+// PromptDiff compares two historical prompts.
+type PromptDiff struct {
+	From, To PromptHistoryEntry
+	// SourceChanges lists sources that were added, removed or changed between the builds.
+	SourceChanges []string
+	// Unified is empty when the prompt text is identical.
+	Unified string
+}
+
+// PromptHistoryDir returns exports/history/<WI>.
+func PromptHistoryDir(id string) string {
+	return AgentPath(exportsDir, historyDir, id)
+}
+
+// LoadPromptManifest reads a work item's history manifest; a missing manifest is empty.
+func LoadPromptManifest(id string) (PromptManifest, error) {
+	m := PromptManifest{WorkItem: id}
+	if err := readYAML(filepath.Join(PromptHistoryDir(id), historyManifest), &m); err != nil && !os.IsNotExist(err) {
+		return m, err
+	}
+	return m, nil
+}
+
+// recordPromptHistory keeps a copy of every generated prompt with the hashes of its sources.
+func recordPromptHistory(result PromptResult, sources []string) (string, error) {
+	dir := PromptHistoryDir(result.WorkItem)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.%s", now.Format("20060102T150405Z"), result.Profile, promptExtension(result.Format))
+	dest := filepath.Join(dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		dest = uniquePath(dest)
+	}
+	if err := os.WriteFile(dest, []byte(result.Output), 0o644); err != nil {
+		return "", err
+	}
+
+	entry := PromptHistoryEntry{
+		Profile:   result.Profile,
+		Format:    result.Format,
+		CreatedAt: now,
+		Sources:   map[string]string{},
+	}
+	var err error
+	if entry.File, err = filepath.Rel(agentDir, dest); err != nil {
+		return "", err
+	}
+	entry.File = filepath.ToSlash(entry.File)
+	for _, src := range sources {
+		sum, err := fileSHA256(src)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		rel, err := filepath.Rel(agentDir, src)
+		if err != nil {
+			return "", err
+		}
+		entry.Sources[filepath.ToSlash(rel)] = sum
+	}
+
+	manifest, err := LoadPromptManifest(result.WorkItem)
+	if err != nil {
+		return "", err
+	}
+	manifest.Entries = append(manifest.Entries, entry)
+	if err := saveYAML(filepath.Join(dir, historyManifest), manifest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// DiffPrompts compares two prompts from a work item's history. Refs may be history file paths
+// or entry names (with or without extension); missing refs default to the two latest entries.
+func DiffPrompts(id string, refs ...string) (PromptDiff, error) {
+	manifest, err := LoadPromptManifest(id)
+	if err != nil {
+		return PromptDiff{}, err
+	}
+	entries := manifest.Entries
+	var from, to PromptHistoryEntry
+	switch len(refs) {
+	case 0:
+		if len(entries) < 2 {
+			return PromptDiff{}, fmt.Errorf("%s has %d prompt(s) in history; need two to diff", id, len(entries))
+		}
+		from, to = entries[len(entries)-2], entries[len(entries)-1]
+	case 1:
+		if len(entries) == 0 {
+			return PromptDiff{}, fmt.Errorf("%s has no prompt history", id)
+		}
+		if from, err = findHistoryEntry(entries, refs[0]); err != nil {
+			return PromptDiff{}, err
+		}
+		to = entries[len(entries)-1]
+	default:
+		if from, err = findHistoryEntry(entries, refs[0]); err != nil {
+			return PromptDiff{}, err
+		}
+		if to, err = findHistoryEntry(entries, refs[1]); err != nil {
+			return PromptDiff{}, err
+		}
+	}
+
+	a, err := os.ReadFile(AgentPath(from.File))
+	if err != nil {
+		return PromptDiff{}, err
+	}
+	b, err := os.ReadFile(AgentPath(to.File))
+	if err != nil {
+		return PromptDiff{}, err
+	}
+	return PromptDiff{
+		From:          from,
+		To:            to,
+		SourceChanges: sourceChanges(from.Sources, to.Sources),
+		Unified:       unifiedDiff(from.File, to.File, string(a), string(b), 3),
+	}, nil
+}
+
+func findHistoryEntry(entries []PromptHistoryEntry, ref string) (PromptHistoryEntry, error) {
+	ref = filepath.ToSlash(ref)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		base := filepath.Base(e.File)
+		switch {
+		case e.File == ref, strings.HasSuffix(ref, "/"+e.File), base == ref:
+			return e, nil
+		case strings.TrimSuffix(base, "."+promptExtension(e.Format)) == ref:
+			return e, nil
+		}
+	}
+	return PromptHistoryEntry{}, fmt.Errorf("prompt %q not found in history", ref)
+}
+
+func sourceChanges(from, to map[string]string) []string {
+	var changes []string
+	for path, sum := range to {
+		prev, ok := from[path]
+		switch {
+		case !ok:
+			changes = append(changes, "added: "+path)
+		case prev != sum:
+			changes = append(changes, "changed: "+path)
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			changes = append(changes, "removed: "+path)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i][strings.Index(changes[i], " ")+1:] < changes[j][strings.Index(changes[j], " ")+1:]
+	})
+	return changes
+}
+
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return contentSHA256(data), nil
+}
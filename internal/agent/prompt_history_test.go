@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPromptHistoryRecordsEvidenceSources(t *testing.T) {
+	inTempRepo(t)
+	for _, rel := range []string{"evidence/trace.txt", "evidence/notes.txt"} {
+		if err := writeTestEvidence(rel, []byte(rel+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Find("evidence/trace.txt").Frames = []StackFrame{{File: "api/upload.go", Line: 88}}
+	if err := SaveEvidenceIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	wi := NewWorkItemFile("WI-001", "Fix upload", nil)
+	wi.Meta.Evidence = []string{"evidence/trace.txt", "evidence/notes.txt"}
+	if err := SaveWorkItem(wi); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.ActiveWorkItem = "WI-001"
+	if err := SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BuildPrompt(PromptOptions{Stdout: true}); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := LoadPromptManifest("WI-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Entries) != 1 {
+		t.Fatalf("manifest has %d entries, want 1", len(manifest.Entries))
+	}
+	var got []string
+	for src := range manifest.Entries[0].Sources {
+		if strings.HasPrefix(src, "evidence/") {
+			got = append(got, src)
+		}
+	}
+	sort.Strings(got)
+	// notes.txt is only listed by path, so its content does not affect the prompt.
+	want := []string{"evidence/index.yaml", "evidence/trace.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("evidence sources = %q, want %q", got, want)
+	}
+}
+
+func TestEvidenceSources(t *testing.T) {
+	idx := EvidenceIndex{Entries: []EvidenceEntry{
+		{Path: "evidence/report.json", Tests: &TestReport{Failures: []TestFailure{{Test: "TestA"}}}},
+		{Path: "evidence/passing.json", Tests: &TestReport{Total: 3, Passed: 3}},
+		{Path: "evidence/trace.txt", Frames: []StackFrame{{File: "main.go"}}},
+		{Path: "evidence/build.log"},
+	}}
+	evidence := []string{"evidence/build.log", "evidence/passing.json", "evidence/report.json", "evidence/trace.txt", "evidence/other.log"}
+	excerpts := []EvidenceExcerpt{{Path: "evidence/build.log"}, {Path: "evidence/other.log"}}
+	got := evidenceSources(evidence, excerpts, idx)
+	want := []string{"evidence/build.log", "evidence/report.json", "evidence/trace.txt", "evidence/other.log"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("evidenceSources = %q, want %q", got, want)
+	}
+}
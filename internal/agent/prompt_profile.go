@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SectionSetting overrides whether and where a prompt section renders.
+type SectionSetting struct {
+	Include *bool `yaml:"include,omitempty"`
+	// Order replaces the section's default position (task=10, body=20, ... in promptSections order).
+	Order *int `yaml:"order,omitempty"`
+}
+
+// UnmarshalYAML also accepts the shorthand `section: true|false`.
+func (s *SectionSetting) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var include bool
+		if err := node.Decode(&include); err != nil {
+			return fmt.Errorf("line %d: section setting must be true, false, or a mapping", node.Line)
+		}
+		s.Include = &include
+		return nil
+	}
+	type plain SectionSetting
+	return node.Decode((*plain)(s))
+}
+
+// UnmarshalYAML records which keys the profile sets, for mergeProfiles.
+func (p *PromptProfile) UnmarshalYAML(node *yaml.Node) error {
+	type plain PromptProfile
+	if err := node.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	p.set = map[string]bool{}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			p.set[node.Content[i].Value] = true
+		}
+	}
+	return nil
+}
+
+// sets reports whether the profile sets key. Profiles built in Go rather than decoded
+// from YAML fall back to nonZero.
+func (p PromptProfile) sets(key string, nonZero bool) bool {
+	if p.set == nil {
+		return nonZero
+	}
+	return p.set[key]
+}
+
+// ResolvePromptProfile looks up a profile, applies its extends chain, and validates the result.
+func ResolvePromptProfile(set PromptProfileSet, name string) (PromptProfile, error) {
+	profile, err := resolveProfileChain(set, name, nil)
+	if err != nil {
+		return PromptProfile{}, err
+	}
+	if err := validatePromptProfile(profile); err != nil {
+		return PromptProfile{}, fmt.Errorf("prompt profile %q: %w", name, err)
+	}
+	return profile, nil
+}
+
+func resolveProfileChain(set PromptProfileSet, name string, seen []string) (PromptProfile, error) {
+	for _, s := range seen {
+		if s == name {
+			return PromptProfile{}, fmt.Errorf("prompt profile extends cycle: %s -> %s", strings.Join(seen, " -> "), name)
+		}
+	}
+	profile, ok := set.Profiles[name]
+	if !ok {
+		if len(seen) > 0 {
+			return PromptProfile{}, fmt.Errorf("prompt profile %q extends unknown profile %q", seen[len(seen)-1], name)
+		}
+		return PromptProfile{}, fmt.Errorf("prompt profile %q not found", name)
+	}
+	if profile.Extends == "" {
+		return profile, nil
+	}
+	parent, err := resolveProfileChain(set, profile.Extends, append(seen, name))
+	if err != nil {
+		return PromptProfile{}, err
+	}
+	return mergeProfiles(parent, profile), nil
+}
+
+// mergeProfiles overlays child on parent: keys the child sets win, even false, 0 or empty,
+// and sections merge per key.
+func mergeProfiles(parent, child PromptProfile) PromptProfile {
+	out := parent
+	out.set = nil
+	out.Extends = child.Extends
+	if child.sets("description", child.Description != "") {
+		out.Description = child.Description
+	}
+	if child.sets("include_architecture", child.IncludeArchitecture) {
+		out.IncludeArchitecture = child.IncludeArchitecture
+	}
+	if child.sets("include_standards", child.IncludeStandards) {
+		out.IncludeStandards = child.IncludeStandards
+	}
+	if child.sets("all_standards", child.AllStandards) {
+		out.AllStandards = child.AllStandards
+	}
+	if child.sets("detail", child.Detail != "") {
+		out.Detail = child.Detail
+	}
+	if child.sets("template", child.Template != "") {
+		out.Template = child.Template
+	}
+	if child.sets("format", child.Format != "") {
+		out.Format = child.Format
+	}
+	if child.sets("model", child.Model != "") {
+		out.Model = child.Model
+	}
+	if child.sets("max_tokens", child.MaxTokens != 0) {
+		out.MaxTokens = child.MaxTokens
+	}
+	if child.sets("tokenizer", child.Tokenizer != "") {
+		out.Tokenizer = child.Tokenizer
+	}
+	if child.sets("trim_order", len(child.TrimOrder) > 0) {
+		out.TrimOrder = child.TrimOrder
+	}
+	if child.sets("body_sections", len(child.BodySections) > 0) {
+		out.BodySections = child.BodySections
+	}
+	if child.sets("health_min_severity", child.HealthMinSeverity != "") {
+		out.HealthMinSeverity = child.HealthMinSeverity
+	}
+	if child.sets("evidence_excerpts", len(child.EvidenceExcerpts) > 0) {
+		out.EvidenceExcerpts = child.EvidenceExcerpts
+	}
+	if child.sets("excerpts_max_bytes", child.ExcerptsMaxBytes != 0) {
+		out.ExcerptsMaxBytes = child.ExcerptsMaxBytes
+	}
+	out.Sections = make(map[string]SectionSetting, len(parent.Sections)+len(child.Sections))
+	for k, v := range parent.Sections {
+		out.Sections[k] = v
+	}
+	for k, v := range child.Sections {
+		merged := out.Sections[k]
+		if v.Include != nil {
+			merged.Include = v.Include
+		}
+		if v.Order != nil {
+			merged.Order = v.Order
+		}
+		out.Sections[k] = merged
+	}
+	return out
+}
+
+func validatePromptProfile(p PromptProfile) error {
+	if err := validateTrimOrder(p.TrimOrder); err != nil {
+		return err
+	}
+	if err := validateDetail(p.Detail); err != nil {
+		return err
+	}
+	if p.Template != "" {
+		if _, err := PromptTemplatePath(p.Template); err != nil {
+			return err
+		}
+	}
+	if p.Format != "" {
+		if err := validatePromptFormat(p.Format); err != nil {
+			return err
+		}
+	}
+	if p.HealthMinSeverity != "" {
+		if err := ValidateSeverity(p.HealthMinSeverity); err != nil {
+			return fmt.Errorf("health_min_severity: %w", err)
+		}
+	}
+	if err := validateExcerptPolicies(p.EvidenceExcerpts); err != nil {
+		return err
+	}
+	var unknown []string
+	for name := range p.Sections {
+		if !isPromptSection(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown section(s) %s in sections (known: %s)", strings.Join(unknown, ", "), strings.Join(promptSections, ", "))
+	}
+	return nil
+}
+
+// sectionPlan returns the sections a profile renders, in order.
+func sectionPlan(p PromptProfile) []string {
+	type planned struct {
+		name  string
+		order int
+	}
+	var plan []planned
+	for i, name := range promptSections {
+		setting := p.Sections[name]
+		if !sectionIncluded(p, name, setting) {
+			continue
+		}
+		order := (i + 1) * 10
+		if setting.Order != nil {
+			order = *setting.Order
+		}
+		plan = append(plan, planned{name, order})
+	}
+	sort.SliceStable(plan, func(i, j int) bool { return plan[i].order < plan[j].order })
+	names := make([]string, len(plan))
+	for i, p := range plan {
+		names[i] = p.name
+	}
+	return names
+}
+
+func sectionIncluded(p PromptProfile, name string, setting SectionSetting) bool {
+	if setting.Include != nil {
+		return *setting.Include
+	}
+	switch {
+	case name == "architecture":
+		return p.IncludeArchitecture
+	case name == "standards":
+		return p.IncludeStandards
+	case name == "body" && len(p.BodySections) > 0:
+		return true
+	case name == "evidence_excerpts":
+		return len(p.EvidenceExcerpts) > 0
+	case fullDetailSections[name]:
+		return detailLevel(p) == DetailFull
+	}
+	return true
+}
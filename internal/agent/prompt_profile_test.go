@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolvePromptProfileChildOverrides(t *testing.T) {
+	const profiles = `
+profiles:
+  base:
+    include_architecture: true
+    include_standards: true
+    all_standards: true
+    detail: full
+    max_tokens: 2000
+    trim_order: [health]
+  tweak:
+    extends: base
+    max_tokens: 0
+    all_standards: false
+    include_architecture: false
+  inherit:
+    extends: base
+    description: Same as base.
+`
+	var set PromptProfileSet
+	if err := yaml.Unmarshal([]byte(profiles), &set); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name                      string
+		maxTokens                 int
+		allStandards, includeArch bool
+	}{
+		{"tweak", 0, false, false},
+		{"inherit", 2000, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ResolvePromptProfile(set, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.MaxTokens != tt.maxTokens || p.AllStandards != tt.allStandards || p.IncludeArchitecture != tt.includeArch {
+				t.Errorf("max_tokens=%d all_standards=%v include_architecture=%v, want %d %v %v",
+					p.MaxTokens, p.AllStandards, p.IncludeArchitecture, tt.maxTokens, tt.allStandards, tt.includeArch)
+			}
+			if !p.IncludeStandards || p.Detail != DetailFull || len(p.TrimOrder) != 1 {
+				t.Errorf("unset keys not inherited: %+v", p)
+			}
+		})
+	}
+}
+
+func TestResolvePromptProfileCycle(t *testing.T) {
+	set := PromptProfileSet{Profiles: map[string]PromptProfile{
+		"a": {Extends: "b"},
+		"b": {Extends: "a"},
+	}}
+	if _, err := ResolvePromptProfile(set, "a"); err == nil {
+		t.Fatal("expected extends cycle error")
+	}
+}
+
+func TestPromptTemplatePathRejectsNonLocal(t *testing.T) {
+	for _, name := range []string{"../../etc/passwd", "/etc/passwd", "a/../../b", ""} {
+		if _, err := PromptTemplatePath(name); err == nil {
+			t.Errorf("PromptTemplatePath(%q) succeeded", name)
+		}
+		set := PromptProfileSet{Profiles: map[string]PromptProfile{"p": {Template: name}}}
+		if _, err := ResolvePromptProfile(set, "p"); name != "" && err == nil {
+			t.Errorf("profile with template %q resolved", name)
+		}
+	}
+	for name, want := range map[string]string{
+		"review":           filepath.Join(agentDir, promptsDir, "review.tmpl"),
+		"team/review.tmpl": filepath.Join(agentDir, promptsDir, "team", "review.tmpl"),
+	} {
+		got, err := PromptTemplatePath(name)
+		if err != nil || got != want {
+			t.Errorf("PromptTemplatePath(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const promptsDir = "prompts"
+
+// PromptTemplatePath resolves a profile's template reference to .agent/prompts/<name>.tmpl. Names
+// that are absolute or climb out of the prompts directory are rejected.
+func PromptTemplatePath(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("prompt template %q must be a path inside %s", name, AgentPath(promptsDir))
+	}
+	if filepath.Ext(name) != ".tmpl" {
+		name += ".tmpl"
+	}
+	return AgentPath(promptsDir, name), nil
+}
+
+// PromptTemplateProblem is a validation finding for one profile's template.
+type PromptTemplateProblem struct {
+	Profile string
+	// Message is prefixed with file:line when the problem can be located.
+	Message string
+	Warning bool
+}
+
+// loadPromptTemplate parses the built-in layout, then the profile's repo template on top so
+// its {{define}} blocks replace the built-in sections. It returns the repo template path, if any.
+func loadPromptTemplate(profile PromptProfile) (*template.Template, string, error) {
+	tpl, err := template.New("prompt").Funcs(promptFuncs()).Parse(promptTemplate)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(profile.Template) == "" {
+		return tpl, "", nil
+	}
+	path, err := PromptTemplatePath(profile.Template)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("prompt template: %w", err)
+	}
+	if _, err := tpl.New(path).Parse(string(data)); err != nil {
+		return nil, path, fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "template: "))
+	}
+	return tpl, path, nil
+}
+
+// ValidatePromptTemplates resolves each profile, then parses and dry-renders its template against the active
+// work item (or a placeholder when none is active). An empty profileName checks all profiles.
+func ValidatePromptTemplates(profileName string) ([]PromptTemplateProblem, error) {
+	profiles, err := LoadPromptProfiles()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles.Profiles))
+	for name := range profiles.Profiles {
+		if profileName == "" || name == profileName {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("prompt profile %q not found", profileName)
+	}
+	sort.Strings(names)
+
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	context, err := LoadContext()
+	if err != nil {
+		return nil, err
+	}
+	wi := NewWorkItemFile("WI-000", "Template validation placeholder", nil)
+	if state.ActiveWorkItem != "" {
+		if wi, err = LoadWorkItem(state.ActiveWorkItem); err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := LoadEvidenceIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []PromptTemplateProblem
+	for _, name := range names {
+		report := func(warning bool, format string, args ...any) {
+			problems = append(problems, PromptTemplateProblem{Profile: name, Message: fmt.Sprintf(format, args...), Warning: warning})
+		}
+		profile, err := ResolvePromptProfile(profiles, name)
+		if err != nil {
+			report(false, "%v", err)
+			continue
+		}
+		tpl, path, err := loadPromptTemplate(profile)
+		if err != nil {
+			report(false, "%v", err)
+			continue
+		}
+		if path != "" {
+			for _, w := range templateWarnings(tpl, path) {
+				report(true, "%s", w)
+			}
+		}
+		if len(profile.BodySections) > 0 {
+			if _, missing := selectMarkdownSections(wi.Body, profile.BodySections); len(missing) > 0 {
+				report(true, "%s has no body section(s) named %s", wi.Meta.ID, strings.Join(missing, ", "))
+			}
+		}
+		data := newPromptData(name, profile, state, wi, context, index)
+		for _, section := range promptSections {
+			if err := tpl.ExecuteTemplate(io.Discard, section, data); err != nil {
+				report(false, "%s", strings.TrimPrefix(err.Error(), "template: "))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// templateWarnings flags repo template content that will never render.
+func templateWarnings(tpl *template.Template, path string) []string {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var warnings []string
+	if root := tpl.Lookup(path); root != nil && root.Tree != nil && hasOutput(root.Tree.Root) {
+		line := lineOf(src, firstOutputPos(root.Tree.Root))
+		warnings = append(warnings, fmt.Sprintf("%s:%d: top-level text outside {{define}} blocks is ignored", path, line))
+	}
+	for _, t := range tpl.Templates() {
+		if t.Tree == nil || t.Tree.ParseName != path || t.Name() == path || isPromptSection(t.Name()) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s:%d: block %q is not a prompt section (%s); it renders only when another block calls it",
+			path, lineOf(src, int(t.Tree.Root.Pos)), t.Name(), strings.Join(promptSections, ", ")))
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+func hasOutput(list *parse.ListNode) bool {
+	return firstOutputPos(list) >= 0
+}
+
+func firstOutputPos(list *parse.ListNode) int {
+	if list == nil {
+		return -1
+	}
+	for _, n := range list.Nodes {
+		if text, ok := n.(*parse.TextNode); ok && len(bytes.TrimSpace(text.Text)) == 0 {
+			continue
+		}
+		return int(n.Position())
+	}
+	return -1
+}
+
+func lineOf(src []byte, pos int) int {
+	if pos < 0 || pos > len(src) {
+		return 1
+	}
+	return 1 + bytes.Count(src[:pos], []byte("\n"))
+}
@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files under testdata/")
+
+// promptFixture returns fixed inputs with enough list items to show summary truncation.
+func promptFixture() (State, *WorkItemFile, Context, EvidenceIndex) {
+	started := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	stopped := started.Add(90 * time.Minute)
+	state := State{
+		ActiveWorkItem: "WI-007",
+		LastSummary:    "Reproduced the overflow with a 2 GiB upload.",
+		Health: HealthSnapshot{
+			Status: "degraded",
+			Issues: []HealthIssue{
+				{ID: "H-1", Summary: "Upload latency above SLO", Severity: SeverityHigh, Since: started},
+				{ID: "H-2", Summary: "Flaky retry test", Severity: SeverityLow, Since: started},
+			},
+		},
+	}
+	wi := &WorkItemFile{
+		Meta: WorkItem{
+			ID:        "WI-007",
+			Title:     "Fix upload size overflow in api handler",
+			Intent:    []string{"bugfix", "backend"},
+			Status:    "active",
+			CreatedAt: started,
+			Evidence: []string{
+				"evidence/upload.log",
+				"evidence/report.json",
+				"evidence/trace.txt",
+				"evidence/heap.txt",
+			},
+			AcceptanceCriteria: []string{
+				"Uploads over 2 GiB are rejected with 413.",
+				"Sizes are parsed as int64.",
+				"Regression test covers the boundary.",
+				"Error message names the limit.",
+			},
+			Sessions: []WorkSession{{StartedAt: started, StoppedAt: &stopped, Summary: "Found the int32 cast."}},
+		},
+		Body: "## Notes\nThe size header is parsed with strconv.Atoi.\n\n## Repro\ncurl -T big.bin localhost:8080/upload",
+	}
+	var ctx Context
+	ctx.Project.Name = "uploader"
+	ctx.Project.Summary = "File upload service."
+	ctx.Architecture = Architecture{Style: "layered", Version: "v2", Notes: "HTTP handlers call storage services."}
+	ctx.Standards = map[string][]string{
+		"backend":  {"Return typed errors.", "Log with request IDs."},
+		"frontend": {"Use hooks."},
+		"shared":   {"Document API limits."},
+	}
+	ctx.StandardsByIntent = map[string][]string{"backend": {"backend"}}
+	ctx.Constraints = []string{"Keep the public API stable.", "No new dependencies."}
+	ctx.QualityGates = stringGates("Tests pass.", "Lint is clean.", "No breaking API changes.", "Benchmarks do not regress.")
+	index := EvidenceIndex{Entries: []EvidenceEntry{
+		{
+			Path: "evidence/report.json",
+			Note: "go test -json",
+			Tests: &TestReport{Format: "go-test-json", Total: 5, Passed: 1, Failed: 4, Failures: []TestFailure{
+				{Package: "example.com/up/api", Test: "TestUploadLimit", File: "api/upload_test.go", Line: 42, Message: "got 200, want 413"},
+				{Package: "example.com/up/api", Test: "TestUploadZero"},
+				{Package: "example.com/up/api", Test: "TestUploadMax"},
+				{Package: "example.com/up/store", Test: "TestPutLarge"},
+			}},
+		},
+		{
+			Path:   "evidence/trace.txt",
+			Frames: []StackFrame{{File: "api/upload.go", Line: 88, Function: "api.parseSize"}, {File: "api/upload.go", Line: 120}},
+		},
+	}}
+	return state, wi, ctx, index
+}
+
+// writeRepoFiles creates empty files so evidence paths resolve to repo files.
+func writeRepoFiles(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPromptDetailGolden(t *testing.T) {
+	tok, err := LoadTokenizer("chars4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test failure files must exist in the repo to reach Likely Files.
+	inTempRepo(t)
+	writeRepoFiles(t, "api/upload.go", "api/upload_test.go")
+	for _, detail := range []string{DetailSummary, DetailBalanced, DetailFull} {
+		t.Run(detail, func(t *testing.T) {
+			profile := PromptProfile{IncludeArchitecture: true, IncludeStandards: true, Detail: detail}
+			state, wi, ctx, index := promptFixture()
+			data := newPromptData("test", profile, state, wi, ctx, index)
+			tpl, _, err := loadPromptTemplate(profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sections, err := renderSections(tpl, data, profile, tok)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := joinSections(sections)
+
+			golden := filepath.Join(testdata, "prompt_"+detail+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s prompt differs from %s:\n%s", detail, golden, unifiedDiff(golden, "got", string(want), got, 3))
+			}
+		})
+	}
+}
+
+func TestNewPromptDataSummaryKeepsHardRules(t *testing.T) {
+	state, wi, ctx, index := promptFixture()
+	ctx.Constraints = []string{"One.", "Two.", "Three.", "Four."}
+	data := newPromptData("cheap", PromptProfile{Detail: DetailSummary}, state, wi, ctx, index)
+	// The context constraints plus the three built-in rules, none elided.
+	if len(data.Constraints) != 7 {
+		t.Errorf("Constraints = %q, want all 7", data.Constraints)
+	}
+	if len(data.TaskAcceptance) != 4 {
+		t.Errorf("TaskAcceptance = %q, want all 4", data.TaskAcceptance)
+	}
+	if len(data.QualityGates) != summaryListItems+1 {
+		t.Errorf("QualityGates = %q, want %d items and a more marker", data.QualityGates, summaryListItems)
+	}
+}
+
+func TestNewPromptDataResolvesFailureFiles(t *testing.T) {
+	inTempRepo(t)
+	writeRepoFiles(t, "services/api/upload_test.go", "store/put_test.go")
+	state, wi, ctx, _ := promptFixture()
+	wi.Meta.Title = "Fix upload"
+	index := EvidenceIndex{Entries: []EvidenceEntry{{
+		Path: "evidence/report.json",
+		Tests: &TestReport{Failures: []TestFailure{
+			// CI checkout path: matched by suffix.
+			{Test: "TestUploadLimit", File: "/home/runner/work/up/services/api/upload_test.go", Line: 42},
+			{Test: "TestPutLarge", File: "store/put_test.go", Line: 7},
+			{Test: "TestGone", File: "legacy/gone_test.go", Line: 3},
+			{Test: "TestRuntime", File: "/usr/local/go/src/testing/testing.go", Line: 1},
+		}},
+	}}}
+	data := newPromptData("test", PromptProfile{}, state, wi, ctx, index)
+	var located []string
+	for _, f := range data.LikelyFiles {
+		if strings.Contains(f, ":") {
+			located = append(located, f)
+		}
+	}
+	want := []string{"services/api/upload_test.go:42", "store/put_test.go:7"}
+	if strings.Join(located, ",") != strings.Join(want, ",") {
+		t.Errorf("located likely files = %q, want %q", located, want)
+	}
+	if len(data.FailingTests) != 4 {
+		t.Errorf("FailingTests = %q, want all 4 failures listed", data.FailingTests)
+	}
+}
+
+func budgetSections() []PromptSection {
+	bullets := func(title, word string, n int) string {
+		lines := []string{title + ":"}
+		for i := 1; i <= n; i++ {
+			lines = append(lines, fmt.Sprintf("- %s %d %s", word, i, strings.Repeat("x", 40)))
+		}
+		return strings.Join(lines, "\n")
+	}
+	return []PromptSection{
+		{Name: "task", Text: "Task: Fix upload size overflow (WI-007)"},
+		{Name: "project", Text: "Project: uploader\n" + strings.Repeat("File upload service. ", 10)},
+		{Name: "evidence", Text: bullets("Evidence", "log", 5)},
+		{Name: "standards", Text: bullets("Standards", "rule", 6)},
+		{Name: "health", Text: bullets("Health Issues", "issue", 4)},
+	}
+}
+
+func TestFitTokenBudget(t *testing.T) {
+	tok, err := LoadTokenizer("chars4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := []string{"health", "standards", "evidence", "project"}
+	// budgetAfter measures the prompt after applying the given summaries and drops by hand.
+	budgetAfter := func(summarize, drop []string) int {
+		var kept []PromptSection
+		for _, s := range budgetSections() {
+			if containsString(drop, s.Name) {
+				continue
+			}
+			if containsString(summarize, s.Name) {
+				s.Text = summarizeSection(s.Text, trimmedListItems)
+			}
+			kept = append(kept, s)
+		}
+		return tok.Count(joinSections(kept))
+	}
+	cases := []struct {
+		name        string
+		maxTokens   int
+		wantNames   []string
+		wantTrimmed []string
+	}{
+		{
+			name:      "unlimited",
+			maxTokens: 0,
+			wantNames: []string{"task", "project", "evidence", "standards", "health"},
+		},
+		{
+			name:      "fits",
+			maxTokens: budgetAfter(nil, nil),
+			wantNames: []string{"task", "project", "evidence", "standards", "health"},
+		},
+		{
+			// Summarizing stops as soon as the prompt fits: evidence keeps all its items.
+			name:        "summarize first in trim order",
+			maxTokens:   budgetAfter([]string{"health", "standards"}, nil),
+			wantNames:   []string{"task", "project", "evidence", "standards", "health"},
+			wantTrimmed: []string{"health (summarized)", "standards (summarized)"},
+		},
+		{
+			// Prose cannot be summarized, so every list is summarized before anything is dropped.
+			name:        "drop after summarizing",
+			maxTokens:   budgetAfter([]string{"health", "standards", "evidence"}, []string{"health", "standards"}),
+			wantNames:   []string{"task", "project", "evidence"},
+			wantTrimmed: []string{"health (dropped)", "standards (dropped)", "evidence (summarized)"},
+		},
+		{
+			name:        "sections outside trim order stay",
+			maxTokens:   1,
+			wantNames:   []string{"task"},
+			wantTrimmed: []string{"health (dropped)", "standards (dropped)", "evidence (dropped)", "project (dropped)"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sections, trimmed := fitTokenBudget(budgetSections(), order, tc.maxTokens, tok)
+			var names []string
+			for _, s := range sections {
+				names = append(names, s.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.wantNames, ",") {
+				t.Errorf("sections = %q, want %q", names, tc.wantNames)
+			}
+			if strings.Join(trimmed, ",") != strings.Join(tc.wantTrimmed, ",") {
+				t.Errorf("trimmed = %q, want %q", trimmed, tc.wantTrimmed)
+			}
+			if tc.maxTokens > 1 {
+				if got := tok.Count(joinSections(sections)); got > tc.maxTokens {
+					t.Errorf("prompt has %d tokens, budget %d", got, tc.maxTokens)
+				}
+			}
+		})
+	}
+}
+
+func TestSummarizeSection(t *testing.T) {
+	text := "Evidence:\n- a\n- b\n- c\n- d\n- e"
+	if got, want := summarizeSection(text, 3), "Evidence:\n- a\n- b\n- c\n- ... (2 more)"; got != want {
+		t.Errorf("summarizeSection = %q, want %q", got, want)
+	}
+	if got := summarizeSection("Evidence:\n- a\n- b", 3); got != "Evidence:\n- a\n- b" {
+		t.Errorf("short lists must be unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	redactionFile = "redaction.yaml"
+	// redactedPrefix starts every replacement, so rescanning redacted evidence finds nothing new.
+	redactedPrefix = "[REDACTED:"
+)
+
+// Redaction policies for evidence that contains secrets.
+const (
+	// RedactPolicy stores a redacted copy of the evidence.
+	RedactPolicy = "redact"
+	// QuarantinePolicy stores a redacted copy and keeps the original outside the repo.
+	QuarantinePolicy = "quarantine"
+	// RefusePolicy rejects the evidence with a findings report.
+	RefusePolicy = "refuse"
+)
+
+// SecretPattern is a detector; Group selects the submatch to redact (0 redacts the whole match).
+type SecretPattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+	Group int    `yaml:"group,omitempty"`
+}
+
+// RedactionConfig is read from .agent/redaction.yaml; its patterns extend the built-in set.
+type RedactionConfig struct {
+	Policy   string          `yaml:"policy,omitempty"`
+	Patterns []SecretPattern `yaml:"patterns,omitempty"`
+}
+
+// SecretFinding is one detected secret.
+type SecretFinding struct {
+	Detector string
+	Line     int
+}
+
+var builtInSecretPatterns = []SecretPattern{
+	{Name: "private-key", Regex: `-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`},
+	{Name: "aws-access-key-id", Regex: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-access-key", Regex: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`, Group: 1},
+	{Name: "bearer-token", Regex: `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{16,}=*)`, Group: 1},
+	{Name: "url-password", Regex: `\b[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`, Group: 1},
+	{Name: "github-token", Regex: `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`},
+	{Name: "slack-token", Regex: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{Name: "jwt", Regex: `\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`},
+	{Name: "password-assignment", Regex: `(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token)["']?\s*[:=]\s*["']?([^\s"',;]{8,})`, Group: 1},
+}
+
+type secretDetector struct {
+	name  string
+	re    *regexp.Regexp
+	group int
+}
+
+// LoadRedactionConfig reads .agent/redaction.yaml, defaulting to the redact policy when absent.
+func LoadRedactionConfig() (RedactionConfig, error) {
+	var cfg RedactionConfig
+	if err := readYAML(AgentPath(redactionFile), &cfg); err != nil && !os.IsNotExist(err) {
+		return cfg, fmt.Errorf("%s: %w", AgentPath(redactionFile), err)
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = RedactPolicy
+	}
+	switch cfg.Policy {
+	case RedactPolicy, QuarantinePolicy, RefusePolicy:
+	default:
+		return cfg, fmt.Errorf("%s: unknown policy %q (known: redact, quarantine, refuse)", AgentPath(redactionFile), cfg.Policy)
+	}
+	return cfg, nil
+}
+
+func (cfg RedactionConfig) detectors() ([]secretDetector, error) {
+	var out []secretDetector
+	for _, p := range append(append([]SecretPattern(nil), builtInSecretPatterns...), cfg.Patterns...) {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", p.Name, err)
+		}
+		if p.Group > re.NumSubexp() {
+			return nil, fmt.Errorf("redaction pattern %q: group %d out of range", p.Name, p.Group)
+		}
+		out = append(out, secretDetector{name: p.Name, re: re, group: p.Group})
+	}
+	return out, nil
+}
+
+// ScanSecrets finds secrets in data and returns the findings and a redacted copy.
+// Binary data is not scanned.
+func ScanSecrets(data []byte, cfg RedactionConfig) ([]SecretFinding, []byte, error) {
+	if IsBinary(data) {
+		return nil, data, nil
+	}
+	detectors, err := cfg.detectors()
+	if err != nil {
+		return nil, nil, err
+	}
+	type span struct {
+		start, end int
+		name       string
+	}
+	var spans []span
+	for _, d := range detectors {
+		for _, m := range d.re.FindAllSubmatchIndex(data, -1) {
+			start, end := m[2*d.group], m[2*d.group+1]
+			if start < 0 || bytes.HasPrefix(data[start:end], []byte(redactedPrefix)) {
+				continue
+			}
+			spans = append(spans, span{start, end, d.name})
+		}
+	}
+	if len(spans) == 0 {
+		return nil, data, nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var findings []SecretFinding
+	var out bytes.Buffer
+	pos := 0
+	for _, s := range spans {
+		if s.start < pos {
+			// Overlaps a span already redacted by an earlier detector.
+			continue
+		}
+		findings = append(findings, SecretFinding{Detector: s.name, Line: 1 + bytes.Count(data[:s.start], []byte("\n"))})
+		out.Write(data[pos:s.start])
+		fmt.Fprintf(&out, "%s%s]", redactedPrefix, s.name)
+		pos = s.end
+	}
+	out.Write(data[pos:])
+	return findings, out.Bytes(), nil
+}
+
+// FormatFindings renders findings as "name:line: detector" lines.
+func FormatFindings(name string, findings []SecretFinding) string {
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s", name, f.Line, f.Detector))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quarantineOriginal keeps an unredacted copy outside the repo, under the user cache directory.
+func quarantineOriginal(name string, data []byte) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	repo, err := filepath.Abs(".")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, "ctx", "quarantine", filepath.Base(repo)+"-"+contentSHA256([]byte(repo))[:8])
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z")+"-"+filepath.Base(name))
+	if err := os.WriteFile(dest, data, 0o600); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// SecretScanResult reports findings for one stored evidence file.
+type SecretScanResult struct {
+	Path     string
+	Findings []SecretFinding
+}
+
+// ScanEvidence audits every file under .agent/evidence; with redact it rewrites them in place
+// and updates their index entries.
+func ScanEvidence(redact bool) ([]SecretScanResult, error) {
+	cfg, err := LoadRedactionConfig()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return nil, err
+	}
+	indexChanged := false
+	var results []SecretScanResult
+	root := AgentPath(evidenceDir)
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || path == AgentPath(evidenceDir, evidenceIndexFile) {
+			return nil
+		}
+		rel, err := filepath.Rel(agentDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		data, err := ReadEvidence(rel)
+		if err != nil {
+			return err
+		}
+		findings, redacted, err := ScanSecrets(data, cfg)
+		if err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			return nil
+		}
+		results = append(results, SecretScanResult{Path: rel, Findings: findings})
+		if !redact {
+			return nil
+		}
+		sum, size := contentSHA256(redacted), int64(len(redacted))
+		if strings.HasSuffix(rel, gzipExt) {
+			if redacted, err = gzipBytes(redacted); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(path, redacted, 0o644); err != nil {
+			return err
+		}
+		// The index describes the content, so it follows the rewrite.
+		if entry := idx.Find(rel); entry != nil {
+			entry.SHA256, entry.Size = sum, size
+			indexChanged = true
+		}
+		return nil
+	})
+	if indexChanged {
+		if saveErr := SaveEvidenceIndex(idx); err == nil {
+			err = saveErr
+		}
+	}
+	return results, err
+}
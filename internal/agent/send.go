@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds a chat request; local models can be slow to answer.
+const sendTimeout = 10 * time.Minute
+
+// SendResult describes a prompt posted to a local chat server.
+type SendResult struct {
+	StatusCode int
+	// Evidence is the path, relative to .agent, where the response body was saved.
+	Evidence string
+}
+
+// SendChatPrompt posts a chat-json body to a loopback URL and saves the response as evidence
+// on the work item. Non-loopback hosts are refused to keep ctx offline.
+func SendChatPrompt(rawURL, workItemID string, body []byte) (SendResult, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return SendResult{}, fmt.Errorf("invalid --send URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return SendResult{}, fmt.Errorf("--send URL must use http or https, got %q", u.Scheme)
+	}
+	if !isLoopbackHost(u.Hostname()) {
+		return SendResult{}, fmt.Errorf("refusing to send prompt to %q: only localhost/loopback servers are allowed", u.Hostname())
+	}
+
+	client := &http.Client{Timeout: sendTimeout, CheckRedirect: loopbackRedirect}
+	resp, err := client.Post(u.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	name := fmt.Sprintf("%s-chat-%s.json", workItemID, time.Now().UTC().Format("20060102T150405Z"))
+	stored, err := WriteEvidence(name, payload, EvidenceEntry{Source: u.String()})
+	if err != nil {
+		return SendResult{}, err
+	}
+	rel := stored.Path
+	if err := AttachEvidence(workItemID, rel); err != nil {
+		return SendResult{}, err
+	}
+	result := SendResult{StatusCode: resp.StatusCode, Evidence: rel}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("chat server returned %s (response saved to %s)", resp.Status, rel)
+	}
+	return result, nil
+}
+
+// loopbackRedirect stops redirects that would carry the prompt off the machine.
+func loopbackRedirect(req *http.Request, via []*http.Request) error {
+	if !isLoopbackHost(req.URL.Hostname()) {
+		return fmt.Errorf("refusing redirect to %q: only localhost/loopback servers are allowed", req.URL.Hostname())
+	}
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
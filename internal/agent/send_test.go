@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendChatPromptRefusesOffHostRedirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/v1/chat/completions", http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	_, err := SendChatPrompt(srv.URL, "WI-001", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "refusing redirect") {
+		t.Fatalf("err = %v, want refused redirect", err)
+	}
+}
+
+func TestSendChatPromptRefusesRemoteURL(t *testing.T) {
+	_, err := SendChatPrompt("http://example.com/v1/chat/completions", "WI-001", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "only localhost/loopback") {
+		t.Fatalf("err = %v, want loopback refusal", err)
+	}
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	tests := map[string]bool{
+		"localhost":   true,
+		"LOCALHOST":   true,
+		"127.0.0.1":   true,
+		"127.1.2.3":   true,
+		"::1":         true,
+		"example.com": false,
+		"10.0.0.1":    false,
+		"":            false,
+	}
+	for host, want := range tests {
+		if got := isLoopbackHost(host); got != want {
+			t.Errorf("isLoopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
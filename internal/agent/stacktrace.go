@@ -0,0 +1,288 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxStackFrames caps the frames kept per evidence file.
+const maxStackFrames = 20
+
+// maxRepoFiles bounds the repo walk used to resolve frame paths by suffix.
+const maxRepoFiles = 50000
+
+// StackFrame is a stack trace frame that maps to a file in this repo.
+type StackFrame struct {
+	File     string `yaml:"file"`
+	Line     int    `yaml:"line"`
+	Function string `yaml:"function,omitempty"`
+}
+
+var (
+	// goroutine frames: "\t/abs/path/file.go:123 +0x1d", preceded by the function line.
+	goFramePattern = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// at com.acme.Foo.bar(Foo.java:42)
+	javaFramePattern = regexp.MustCompile(`^\s*at ([\w$.<>/]+)\(([\w$]+\.(?:java|kt|scala|groovy)):(\d+)\)`)
+	// File "/app/svc/handler.py", line 12, in handle
+	pythonFramePattern = regexp.MustCompile(`^\s*File "([^"]+\.py)", line (\d+)(?:, in (\S+))?`)
+	// at handle (/app/src/server.js:12:5) or at /app/src/server.js:12:5
+	nodeFramePattern = regexp.MustCompile(`^\s*at (?:(.+?) \()?(?:file://)?([^()\s]+\.(?:js|mjs|cjs|ts|tsx|jsx)):(\d+):\d+\)?$`)
+)
+
+// vendoredPathMarkers identify frames in dependencies or runtimes rather than repo code.
+var vendoredPathMarkers = []string{"/pkg/mod/", "/site-packages/", "/dist-packages/", "/node_modules/", "/usr/local/go/", "/usr/lib/go", "node:internal", "<frozen"}
+
+// rawFrame is a parsed frame before it is resolved against the repo.
+type rawFrame struct {
+	candidates []string
+	line       int
+	function   string
+}
+
+// stackFrames extracts Go, Java, Python and Node frames from text and keeps those that
+// resolve to repo files, in order of appearance.
+func stackFrames(text string) []StackFrame {
+	raw := parseStackFrames(text)
+	if len(raw) == 0 {
+		return nil
+	}
+	resolver := newRepoResolver()
+	var out []StackFrame
+	seen := map[string]bool{}
+	for _, f := range raw {
+		file, ok := resolver.resolve(f.candidates)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", file, f.line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, StackFrame{File: file, Line: f.line, Function: f.function})
+		if len(out) == maxStackFrames {
+			break
+		}
+	}
+	return out
+}
+
+func parseStackFrames(text string) []rawFrame {
+	var frames []rawFrame
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if m := goFramePattern.FindStringSubmatch(line); m != nil {
+			fn := ""
+			if i > 0 {
+				fn = strings.TrimSpace(lines[i-1])
+				if j := strings.LastIndex(fn, "("); j > 0 {
+					fn = fn[:j]
+				}
+			}
+			frames = append(frames, newRawFrame(m[2], fn, m[1]))
+			continue
+		}
+		if m := javaFramePattern.FindStringSubmatch(line); m != nil {
+			// The package path is the qualified method minus class and method names.
+			parts := strings.Split(m[1], ".")
+			var candidates []string
+			if len(parts) > 2 {
+				candidates = append(candidates, path.Join(append(parts[:len(parts)-2:len(parts)-2], m[2])...))
+			}
+			candidates = append(candidates, m[2])
+			line, _ := strconv.Atoi(m[3])
+			frames = append(frames, rawFrame{candidates: candidates, line: line, function: m[1]})
+			continue
+		}
+		if m := pythonFramePattern.FindStringSubmatch(line); m != nil {
+			frames = append(frames, newRawFrame(m[2], m[3], m[1]))
+			continue
+		}
+		if m := nodeFramePattern.FindStringSubmatch(line); m != nil {
+			frames = append(frames, newRawFrame(m[3], m[1], m[2]))
+		}
+	}
+	return frames
+}
+
+func newRawFrame(line, function, file string) rawFrame {
+	n, _ := strconv.Atoi(line)
+	return rawFrame{candidates: []string{file}, line: n, function: function}
+}
+
+// repoResolver maps frame paths to repo-relative files.
+type repoResolver struct {
+	root  string
+	files []string // lazily loaded repo-relative paths
+}
+
+func newRepoResolver() *repoResolver {
+	root, _ := filepath.Abs(".")
+	return &repoResolver{root: root}
+}
+
+// resolve returns the first candidate that maps to a repo file.
+func (r *repoResolver) resolve(candidates []string) (string, bool) {
+	for _, c := range candidates {
+		if file, ok := r.resolveOne(c); ok {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+func (r *repoResolver) resolveOne(p string) (string, bool) {
+	for _, marker := range vendoredPathMarkers {
+		if strings.Contains(p, marker) {
+			return "", false
+		}
+	}
+	native := filepath.FromSlash(p)
+	if filepath.IsAbs(native) {
+		if rel, err := filepath.Rel(r.root, native); err == nil && !strings.HasPrefix(rel, "..") {
+			if isRepoFile(rel) {
+				return filepath.ToSlash(rel), true
+			}
+		}
+	} else if isRepoFile(native) {
+		return filepath.ToSlash(filepath.Clean(native)), true
+	}
+
+	// Traces from CI or containers use other roots; match the longest path suffix instead.
+	// Absolute paths must share at least two components so a bare file name is not enough.
+	parts := strings.Split(strings.Trim(filepath.ToSlash(p), "/"), "/")
+	minMatch := len(parts)
+	if filepath.IsAbs(native) || minMatch > 2 {
+		minMatch = min(minMatch, 2)
+	}
+	best, bestScore := "", 0
+	for _, f := range r.repoFiles() {
+		score := suffixComponents(parts, strings.Split(f, "/"))
+		if score > bestScore || (score == bestScore && score > 0 && len(f) < len(best)) {
+			best, bestScore = f, score
+		}
+	}
+	if bestScore >= minMatch && bestScore > 0 {
+		return best, true
+	}
+	return "", false
+}
+
+func (r *repoResolver) repoFiles() []string {
+	if r.files != nil {
+		return r.files
+	}
+	r.files = []string{}
+	_ = walkRepoFiles(func(rel string, d os.DirEntry) error {
+		if len(r.files) >= maxRepoFiles {
+			return filepath.SkipAll
+		}
+		r.files = append(r.files, rel)
+		return nil
+	})
+	return r.files
+}
+
+// walkRepoFiles calls fn with the slash-separated path of each repo file, skipping .git, .agent,
+// and dependency or build output directories. Unreadable entries are skipped.
+func walkRepoFiles(fn func(rel string, d os.DirEntry) error) error {
+	return filepath.WalkDir(".", func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", agentDir, "node_modules", "vendor", "target", "build", "dist", "__pycache__":
+				if p != "." {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		return fn(filepath.ToSlash(p), d)
+	})
+}
+
+func isRepoFile(rel string) bool {
+	info, err := os.Stat(rel)
+	return err == nil && !info.IsDir()
+}
+
+// suffixComponents counts matching trailing path components.
+func suffixComponents(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// rankedFrameFiles ranks files by how often they appear in frames, then by first appearance,
+// and renders each as path:line[,line...].
+func rankedFrameFiles(frames []StackFrame) []string {
+	type ranked struct {
+		file  string
+		lines []int
+		count int
+		first int
+	}
+	byFile := map[string]*ranked{}
+	var order []*ranked
+	for i, f := range frames {
+		r := byFile[f.File]
+		if r == nil {
+			r = &ranked{file: f.File, first: i}
+			byFile[f.File] = r
+			order = append(order, r)
+		}
+		r.count++
+		if f.Line > 0 && !containsInt(r.lines, f.Line) {
+			r.lines = append(r.lines, f.Line)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].count != order[j].count {
+			return order[i].count > order[j].count
+		}
+		return order[i].first < order[j].first
+	})
+	out := make([]string, len(order))
+	for i, r := range order {
+		out[i] = r.file
+		if len(r.lines) > 0 {
+			nums := make([]string, len(r.lines))
+			for k, n := range r.lines {
+				nums[k] = strconv.Itoa(n)
+			}
+			out[i] += ":" + strings.Join(nums, ",")
+		}
+	}
+	return out
+}
+
+func containsInt(items []int, n int) bool {
+	for _, item := range items {
+		if item == n {
+			return true
+		}
+	}
+	return false
+}
+
+// evidenceFrames collects stack frames from a work item's evidence, in evidence order.
+func evidenceFrames(evidence []string, idx EvidenceIndex) []StackFrame {
+	var out []StackFrame
+	for _, rel := range evidence {
+		if entry := idx.Find(rel); entry != nil {
+			out = append(out, entry.Frames...)
+		}
+	}
+	return out
+}
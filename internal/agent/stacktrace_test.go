@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStackFrames(t *testing.T) {
+	inTempRepo(t)
+	writeRepoFiles(t,
+		"api/upload.go",
+		"cmd/server/main.go",
+		"svc/handler.py",
+		"svc/models/user.py",
+		"web/src/server.js",
+		"web/src/routes/upload.ts",
+	)
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.ToSlash(root)
+	cases := []struct {
+		name, text string
+		want       []StackFrame
+	}{
+		{
+			name: "go panic",
+			text: `panic: runtime error: index out of range [3] with length 3
+
+goroutine 1 [running]:
+example.com/up/api.parseSize(...)
+	/home/runner/work/up/up/api/upload.go:88
+example.com/up/api.(*Handler).ServeHTTP(0xc000010000, {0x6b1f20, 0xc00001e0e0})
+	` + abs + `/api/upload.go:120 +0x1d
+main.main()
+	/build/cmd/server/main.go:14 +0x25
+net/http.(*conn).serve(0xc0000a6000)
+	/usr/local/go/src/net/http/server.go:2009 +0x5f4
+github.com/spf13/cobra.(*Command).Execute(...)
+	/root/go/pkg/mod/github.com/spf13/cobra@v1.8.0/command.go:1039
+`,
+			want: []StackFrame{
+				{File: "api/upload.go", Line: 88, Function: "example.com/up/api.parseSize"},
+				{File: "api/upload.go", Line: 120, Function: "example.com/up/api.(*Handler).ServeHTTP"},
+				{File: "cmd/server/main.go", Line: 14, Function: "main.main"},
+			},
+		},
+		{
+			name: "python traceback",
+			text: `Traceback (most recent call last):
+  File "/usr/lib/python3.11/site-packages/flask/app.py", line 1484, in full_dispatch_request
+    rv = self.dispatch_request()
+  File "/app/svc/handler.py", line 12, in handle
+    user = load(uid)
+  File "svc/models/user.py", line 40, in load
+    raise KeyError(uid)
+  File "<frozen importlib._bootstrap>", line 1, in <module>
+KeyError: 7
+`,
+			want: []StackFrame{
+				{File: "svc/handler.py", Line: 12, Function: "handle"},
+				{File: "svc/models/user.py", Line: 40, Function: "load"},
+			},
+		},
+		{
+			name: "node stack",
+			text: `TypeError: Cannot read properties of undefined (reading 'size')
+    at parseUpload (/srv/app/web/src/routes/upload.ts:31:17)
+    at Layer.handle [as handle_request] (/srv/app/node_modules/express/lib/router/layer.js:95:5)
+    at file:///srv/app/web/src/server.js:12:5
+    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)
+`,
+			want: []StackFrame{
+				{File: "web/src/routes/upload.ts", Line: 31, Function: "parseUpload"},
+				{File: "web/src/server.js", Line: 12},
+			},
+		},
+		{
+			name: "duplicates kept once",
+			text: "\tapi/upload.go:88\n\tapi/upload.go:88\n",
+			want: []StackFrame{{File: "api/upload.go", Line: 88}},
+		},
+		{
+			name: "no frames",
+			text: "FAIL\texample.com/up/api\t0.01s\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := stackFrames(tc.text)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("stackFrames:\n got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRepoResolverSuffixMatches(t *testing.T) {
+	inTempRepo(t)
+	writeRepoFiles(t,
+		"main.go",
+		"cmd/server/main.go",
+		"cmd/worker/main.go",
+		"internal/store/util.go",
+		"pkg/store/util.go",
+		"vendor/store/util.go",
+	)
+	cases := []struct {
+		path string
+		want string
+	}{
+		// Exact repo paths resolve directly.
+		{"cmd/worker/main.go", "cmd/worker/main.go"},
+		{"./cmd/worker/main.go", "cmd/worker/main.go"},
+		// The longest matching suffix wins.
+		{"/ci/checkout/cmd/server/main.go", "cmd/server/main.go"},
+		// Equal suffixes prefer the shorter path.
+		{"/ci/checkout/store/util.go", "pkg/store/util.go"},
+		// A bare relative name may match any file; the shortest wins.
+		{"main.go", "main.go"},
+		{"util.go", "pkg/store/util.go"},
+		// Absolute paths need two matching components, so a shared file name is not enough.
+		{"/elsewhere/lib/main.go", ""},
+		// Longer relative paths also need only two matching components; vendor/ is never walked.
+		{"lib/store/util.go", "pkg/store/util.go"},
+		{"lib/other/util.go", ""},
+		// Dependency and runtime paths never resolve.
+		{"/root/go/pkg/mod/example.com/store/util.go", ""},
+		{"/app/node_modules/store/util.go", ""},
+	}
+	r := newRepoResolver()
+	for _, tc := range cases {
+		got, ok := r.resolveOne(tc.path)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("resolveOne(%q) = %q, %v; want %q", tc.path, got, ok, tc.want)
+		}
+	}
+}
+
+func TestRankedFrameFiles(t *testing.T) {
+	frames := []StackFrame{
+		{File: "api/upload.go", Line: 88},
+		{File: "cmd/main.go", Line: 14},
+		{File: "api/upload.go", Line: 120},
+		{File: "api/upload.go", Line: 88},
+		{File: "store/put.go"},
+		{File: "cmd/main.go", Line: 14},
+	}
+	got := rankedFrameFiles(frames)
+	want := []string{"api/upload.go:88,120", "cmd/main.go:14", "store/put.go"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("rankedFrameFiles = %q, want %q", got, want)
+	}
+}
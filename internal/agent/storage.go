@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -104,7 +104,7 @@ func ensureFreshAgentLayout() error {
 func DefaultState() State {
 	st := State{}
 	st.Health.Status = "unknown"
-	st.Health.Issues = []string{}
+	st.Health.Issues = []HealthIssue{}
 	return st
 }
 
@@ -117,18 +117,23 @@ func DefaultPromptProfiles() PromptProfileSet {
 				IncludeArchitecture: false,
 				IncludeStandards:    false,
 				Detail:              "summary",
+				MaxTokens:           800,
+				Tokenizer:           DefaultTokenizer(),
 			},
 			"standard": {
 				Description:         "Include architecture and standards for balanced prompts.",
 				IncludeArchitecture: true,
 				IncludeStandards:    true,
 				Detail:              "balanced",
+				MaxTokens:           2000,
+				Tokenizer:           DefaultTokenizer(),
 			},
 			"deep": {
 				Description:         "Full context disclosure; include architecture, standards, and constraints.",
 				IncludeArchitecture: true,
 				IncludeStandards:    true,
 				Detail:              "full",
+				Tokenizer:           DefaultTokenizer(),
 			},
 		},
 	}
@@ -165,6 +170,7 @@ func LoadState() (State, error) {
 	if st.Health.Status == "" && len(st.Health.Issues) == 0 {
 		st.Health.Status = "unknown"
 	}
+	normalizeHealth(&st.Health)
 	return st, nil
 }
 
@@ -301,57 +307,237 @@ func defaultWorkItemBody(meta WorkItem) string {
 `, meta.ID, meta.Title)
 }
 
-// CopyEvidence copies a file into the evidence directory and returns the relative path used.
-func CopyEvidence(srcPath string) (string, error) {
-	if err := os.MkdirAll(AgentPath(evidenceDir), 0o755); err != nil {
-		return "", err
-	}
-	base := filepath.Base(srcPath)
-	dest := AgentPath(evidenceDir, base)
+// StoredEvidence describes evidence written under .agent/evidence/.
+type StoredEvidence struct {
+	// Path is relative to .agent.
+	Path     string
+	Findings []SecretFinding
+	Redacted bool
+	// Quarantined is the out-of-tree path of the unredacted original, if kept.
+	Quarantined string
+	// Deduped is set when identical content was already stored at Path.
+	Deduped bool
+	// Compressed is set when the file was gzipped per evidence.compress_over_bytes.
+	Compressed bool
+	// Warnings lists quota problems under the warn policy.
+	Warnings []string
+	// Tests is set when the evidence was recognized as a test report.
+	Tests *TestReport
+	// Frames lists stack trace frames found in the evidence that map to repo files.
+	Frames []StackFrame
+}
 
-	if _, err := os.Stat(dest); err == nil {
-		dest = uniquePath(dest)
+// CopyEvidence copies a file into the evidence directory, applying the redaction policy.
+func CopyEvidence(srcPath, note string) (StoredEvidence, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return StoredEvidence{}, err
 	}
+	meta := EvidenceEntry{Source: filepath.ToSlash(filepath.Clean(srcPath)), Note: note}
+	return storeEvidence(filepath.Base(srcPath), data, meta)
+}
 
-	if err := copyFile(srcPath, dest); err != nil {
-		return "", err
+// WriteEvidence stores generated content under .agent/evidence/, applying the redaction policy.
+// Source and Note from meta are recorded in the evidence index.
+func WriteEvidence(name string, data []byte, meta EvidenceEntry) (StoredEvidence, error) {
+	return storeEvidence(filepath.Base(name), data, meta)
+}
+
+// CopyEvidenceDir copies the files under dir whose base name or relative path matches glob
+// into .agent/evidence/<WI>/<dir base>/, keeping the relative structure.
+func CopyEvidenceDir(workItemID, dir, glob, note string) ([]StoredEvidence, error) {
+	if glob == "" {
+		glob = "*"
 	}
-	rel, err := filepath.Rel(agentDir, dest)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("bad --glob %q: %w", glob, err)
+	}
+	root, err := filepath.Abs(dir)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return rel, nil
+	var stored []StoredEvidence
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		baseMatch, _ := path.Match(glob, path.Base(rel))
+		relMatch, _ := path.Match(glob, rel)
+		if !baseMatch && !relMatch {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		meta := EvidenceEntry{Source: filepath.ToSlash(filepath.Join(dir, rel)), Note: note}
+		s, err := storeEvidence(path.Join(workItemID, filepath.Base(root), rel), data, meta)
+		if err != nil {
+			return err
+		}
+		stored = append(stored, s)
+		return nil
+	})
+	return stored, err
 }
 
-func uniquePath(path string) string {
-	dir := filepath.Dir(path)
-	ext := filepath.Ext(path)
-	base := strings.TrimSuffix(filepath.Base(path), ext)
-	for i := 1; ; i++ {
-		candidate := filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
-		if _, err := os.Stat(candidate); os.IsNotExist(err) {
-			return candidate
+// storeEvidence scans data for secrets, writes it (redacted if needed) to name under
+// .agent/evidence/ and indexes it. Content that is already stored is reused instead of written again.
+func storeEvidence(name string, data []byte, meta EvidenceEntry) (StoredEvidence, error) {
+	name = path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return StoredEvidence{}, fmt.Errorf("evidence name %q escapes the evidence directory", name)
+	}
+	cfg, err := LoadRedactionConfig()
+	if err != nil {
+		return StoredEvidence{}, err
+	}
+	findings, redacted, err := ScanSecrets(data, cfg)
+	if err != nil {
+		return StoredEvidence{}, err
+	}
+	stored := StoredEvidence{Findings: findings}
+	if len(findings) > 0 {
+		if cfg.Policy == RefusePolicy {
+			return stored, fmt.Errorf("refusing evidence %s: %d secret(s) found (policy: refuse)\n%s", name, len(findings), FormatFindings(name, findings))
+		}
+		if cfg.Policy == QuarantinePolicy {
+			if stored.Quarantined, err = quarantineOriginal(name, data); err != nil {
+				return stored, fmt.Errorf("quarantine %s: %w", name, err)
+			}
+		}
+		data = redacted
+		stored.Redacted = true
+	}
+
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return stored, err
+	}
+	sum := contentSHA256(data)
+	// Each command run is its own record, so only plain content is deduplicated.
+	if existing := idx.findContent(sum); existing != nil && meta.Run == nil {
+		// The first note describes the stored content; later adds only fill in a missing one.
+		if existing.Note == "" {
+			existing.Note = meta.Note
+		}
+		stored.Path = existing.Path
+		stored.Deduped = true
+		stored.Tests = existing.Tests
+		stored.Frames = existing.Frames
+		return stored, SaveEvidenceIndex(idx)
+	}
+
+	policy, err := LoadPolicy()
+	if err != nil {
+		return stored, err
+	}
+	content := data
+	if policy.Evidence.shouldCompress(name, data) {
+		if data, err = gzipBytes(data); err != nil {
+			return stored, err
+		}
+		name += gzipExt
+		stored.Compressed = true
+	}
+	problem, err := policy.Evidence.checkQuota(name, int64(len(data)))
+	if err != nil {
+		return stored, err
+	}
+	if problem != "" {
+		if policy.Evidence.OnQuota == QuotaRefuse {
+			return stored, fmt.Errorf("refusing evidence: %s (evidence.on_quota: refuse)", problem)
+		}
+		stored.Warnings = append(stored.Warnings, problem)
+	}
+
+	dest := AgentPath(evidenceDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return stored, err
+	}
+	if _, err := os.Stat(dest); err == nil || name == evidenceIndexFile {
+		dest = uniquePath(dest)
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		return stored, err
+	}
+	rel, err := filepath.Rel(agentDir, dest)
+	if err != nil {
+		return stored, err
+	}
+	stored.Path = filepath.ToSlash(rel)
+
+	entry := meta
+	entry.Path = stored.Path
+	entry.SHA256 = sum
+	entry.Size = int64(len(content))
+	entry.Type = detectEvidenceType(strings.TrimSuffix(name, gzipExt), content)
+	entry.Compressed = stored.Compressed
+	if format := detectTestReport(name, content); format != "" {
+		// Detection is best effort; ctx evidence add --type reports parse errors.
+		if report, err := ParseTestReport(content, format); err == nil {
+			entry.Tests = report
+			stored.Tests = report
 		}
 	}
+	if !IsBinary(content) {
+		entry.Frames = stackFrames(string(content))
+		stored.Frames = entry.Frames
+	}
+	entry.AddedAt = time.Now().UTC()
+	entry.WorkItems = nil
+	idx.Entries = append(idx.Entries, entry)
+	return stored, SaveEvidenceIndex(idx)
 }
 
-func copyFile(src, dest string) error {
-	in, err := os.Open(src)
+// AttachEvidence links an evidence path to a work item and records the link in the index.
+func AttachEvidence(id, rel string) error {
+	wi, err := LoadWorkItem(id)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
+	linked := false
+	for _, e := range wi.Meta.Evidence {
+		if e == rel {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		wi.Meta.Evidence = append(wi.Meta.Evidence, rel)
+		if err := SaveWorkItem(wi); err != nil {
+			return err
+		}
+	}
 
-	out, err := os.Create(dest)
+	idx, err := LoadEvidenceIndex()
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	if entry := idx.Find(rel); entry != nil && entry.addWorkItem(id) {
+		return SaveEvidenceIndex(idx)
+	}
+	return nil
+}
 
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+func uniquePath(path string) string {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
 	}
-	return out.Sync()
 }
 
 // EnsureAgentExists verifies that .agent is present.
@@ -392,12 +578,24 @@ func ListWorkItems() ([]string, error) {
 	return ids, nil
 }
 
-// TouchPromptFile ensures the exports directory exists.
-func TouchPromptFile() (string, error) {
+// TouchPromptFile ensures the exports directory exists and returns the current prompt path for format.
+func TouchPromptFile(format string) (string, error) {
 	if err := os.MkdirAll(AgentPath(exportsDir), 0o755); err != nil {
 		return "", err
 	}
-	return AgentPath(exportsDir, currentPromptFile), nil
+	name := currentPromptFile
+	if format != "" && format != FormatMarkdown {
+		name = strings.TrimSuffix(currentPromptFile, ".md") + "." + promptExtension(format)
+	}
+	return AgentPath(exportsDir, name), nil
+}
+
+// PromptExportPath returns exports/<WI>.<profile>.prompt.<ext> so worktrees and profiles do not collide.
+func PromptExportPath(id, profile, format string) string {
+	if format == "" {
+		format = FormatMarkdown
+	}
+	return AgentPath(exportsDir, fmt.Sprintf("%s.%s.prompt.%s", id, profile, promptExtension(format)))
 }
 
 // NewWorkItemFile constructs a new work item with defaults.
@@ -0,0 +1,35 @@
+package agent
+
+import "testing"
+
+func TestStoreEvidenceDedupeKeepsFirstNote(t *testing.T) {
+	inTempRepo(t)
+	data := []byte("panic: size overflow\n")
+	adds := []struct {
+		name, note, want string
+	}{
+		{"first.log", "", ""},
+		{"second.log", "upload crash on CI", "upload crash on CI"},
+		{"third.log", "same crash, local run", "upload crash on CI"},
+		{"fourth.log", "", "upload crash on CI"},
+	}
+	var path string
+	for i, add := range adds {
+		stored, err := storeEvidence(add.name, data, EvidenceEntry{Note: add.note})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			path = stored.Path
+		} else if !stored.Deduped || stored.Path != path {
+			t.Fatalf("%s: stored %+v, want a dedupe of %s", add.name, stored, path)
+		}
+		idx, err := LoadEvidenceIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := idx.Find(path).Note; got != add.want {
+			t.Errorf("after %s: note = %q, want %q", add.name, got, add.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateLayerSep joins template names composed on the command line, e.g. base+go-service.
+const templateLayerSep = "+"
+
+// errTemplateNotFound is returned for a name that is neither a repo nor a built-in template.
+var errTemplateNotFound = errors.New("template not found")
+
+// loadTemplateLayer reads one template without resolving extends, preferring the repo copy.
+// A template that exists nowhere returns an error wrapping errTemplateNotFound.
+func loadTemplateLayer(name string) (Context, error) {
+	ctx, err := loadRepoTemplate(name)
+	if err == nil {
+		return ctx, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, os.ErrNotExist) {
+		return Context{}, fmt.Errorf("%s: %w", RepoTemplatePath(name), err)
+	}
+	if ctx, ok := builtInTemplate(name); ok {
+		return ctx, nil
+	}
+	return Context{}, fmt.Errorf("%w: %q", errTemplateNotFound, name)
+}
+
+// resolveTemplateChain merges a template over the templates it extends, depth first.
+func resolveTemplateChain(name string, seen []string) (Context, error) {
+	for _, s := range seen {
+		if s == name {
+			return Context{}, fmt.Errorf("template extends cycle: %s -> %s", strings.Join(seen, " -> "), name)
+		}
+	}
+	ctx, err := loadTemplateLayer(name)
+	if err != nil {
+		if len(seen) > 0 && errors.Is(err, errTemplateNotFound) {
+			// Not wrapped: an unknown parent must not fall back to the default template.
+			return Context{}, fmt.Errorf("template %q extends unknown template %q", seen[len(seen)-1], name)
+		}
+		return Context{}, err
+	}
+	if len(ctx.Extends) == 0 {
+		return ctx, nil
+	}
+	var base Context
+	for _, parent := range ctx.Extends {
+		resolved, err := resolveTemplateChain(strings.TrimSpace(parent), append(seen, name))
+		if err != nil {
+			return Context{}, err
+		}
+		base = mergeContexts(base, resolved)
+	}
+	return mergeContexts(base, ctx), nil
+}
+
+// resolveTemplateLayers resolves each named template and merges them left to right.
+func resolveTemplateLayers(names []string) (Context, error) {
+	var out Context
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return Context{}, fmt.Errorf("empty template name in %q", strings.Join(names, templateLayerSep))
+		}
+		ctx, err := resolveTemplateChain(name, nil)
+		if err != nil {
+			return Context{}, err
+		}
+		out = mergeContexts(out, ctx)
+	}
+	return out, nil
+}
+
+// mergeContexts overlays child on parent: set scalars win, lists merge uniquely, and maps merge per key.
+// Quality gates are unique by Key; a child gate overrides the fields it sets on the parent's, in place.
+// The project name and template identify a template, so they are not inherited.
+func mergeContexts(parent, child Context) Context {
+	out := cloneContext(parent)
+	out.Extends = nil
+	out.Project.Name = child.Project.Name
+	out.Project.Template = child.Project.Template
+	if child.Project.Summary != "" {
+		out.Project.Summary = child.Project.Summary
+	}
+	if child.Architecture.Style != "" {
+		out.Architecture.Style = child.Architecture.Style
+	}
+	if child.Architecture.Version != "" {
+		out.Architecture.Version = child.Architecture.Version
+	}
+	if child.Architecture.Notes != "" {
+		out.Architecture.Notes = child.Architecture.Notes
+	}
+	out.Standards = mergeScopes(out.Standards, child.Standards)
+	out.StandardsByIntent = mergeScopes(out.StandardsByIntent, child.StandardsByIntent)
+	out.Constraints = mergeUnique(out.Constraints, child.Constraints)
+	for _, gate := range child.QualityGates {
+		replaced := false
+		for i := range out.QualityGates {
+			if out.QualityGates[i].Key() == gate.Key() {
+				out.QualityGates[i] = mergeGate(out.QualityGates[i], gate)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.QualityGates = append(out.QualityGates, gate)
+		}
+	}
+	return out
+}
+
+// mergeGate overlays the fields a child gate sets on the parent gate with the same key,
+// including explicit zero values such as expect_exit: 0.
+func mergeGate(parent, child QualityGate) QualityGate {
+	out := parent
+	if child.sets("description", child.Description != "") {
+		out.Description = child.Description
+	}
+	if child.sets("command", child.Command != "") {
+		out.Command = child.Command
+	}
+	if child.sets("timeout", child.Timeout != 0) {
+		out.Timeout = child.Timeout
+	}
+	if child.sets("expect_exit", child.ExpectExit != 0) {
+		out.ExpectExit = child.ExpectExit
+	}
+	return out
+}
+
+func mergeScopes(parent, child map[string][]string) map[string][]string {
+	if len(child) == 0 {
+		return parent
+	}
+	out := make(map[string][]string, len(parent)+len(child))
+	for k, v := range parent {
+		out[k] = v
+	}
+	for k, v := range child {
+		out[k] = mergeUnique(out[k], v)
+	}
+	return out
+}
+
+// TemplateYAML renders a template as YAML; resolved merges the templates it extends.
+// Unlike ResolveTemplate, an unknown name is an error rather than the default template.
+func TemplateYAML(name string, resolved bool) ([]byte, error) {
+	composed := strings.Contains(name, templateLayerSep)
+	var ctx Context
+	var err error
+	switch {
+	case resolved && composed:
+		ctx, err = resolveTemplateLayers(strings.Split(name, templateLayerSep))
+	case resolved:
+		ctx, err = resolveTemplateChain(name, nil)
+	case composed:
+		return nil, fmt.Errorf("%q is a composition; use --resolved to show the merged result", name)
+	default:
+		ctx, err = loadTemplateLayer(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resolved {
+		ctx = finalizeTemplateMetadata(ctx, name, name)
+	}
+	return yaml.Marshal(ctx)
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRepoTemplates(t *testing.T, templates map[string]string) {
+	t.Helper()
+	for name, body := range templates {
+		if err := os.MkdirAll(AgentPath(templatesDir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(RepoTemplatePath(name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveTemplateChainErrors(t *testing.T) {
+	inTempRepo(t)
+	writeRepoTemplates(t, map[string]string{
+		"a":      "extends: [b]\n",
+		"b":      "extends: [c]\n",
+		"c":      "extends: [a]\n",
+		"self":   "extends: [self]\n",
+		"orphan": "extends: [missing]\n",
+		"deep":   "extends: [orphan]\n",
+	})
+	cases := []struct {
+		name, want string
+	}{
+		{"a", "template extends cycle: a -> b -> c -> a"},
+		{"self", "template extends cycle: self -> self"},
+		{"orphan", `template "orphan" extends unknown template "missing"`},
+		{"deep", `template "orphan" extends unknown template "missing"`},
+	}
+	for _, tc := range cases {
+		_, err := resolveTemplateChain(tc.name, nil)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("resolveTemplateChain(%q) error = %v, want %q", tc.name, err, tc.want)
+		}
+		if errors.Is(err, errTemplateNotFound) {
+			t.Errorf("resolveTemplateChain(%q) must not report the template itself as not found", tc.name)
+		}
+	}
+	if _, err := resolveTemplateChain("nowhere", nil); !errors.Is(err, errTemplateNotFound) {
+		t.Errorf("unknown top-level template error = %v, want errTemplateNotFound", err)
+	}
+}
+
+func TestResolveTemplateChainMerges(t *testing.T) {
+	inTempRepo(t)
+	writeRepoTemplates(t, map[string]string{
+		"parent": `project:
+  name: parent
+  summary: Parent summary.
+architecture:
+  style: layered
+  notes: Parent notes.
+standards:
+  backend: [Return typed errors.]
+constraints: [Keep the API stable.]
+quality_gates:
+  - Tests pass.
+  - name: lint
+    command: golangci-lint run
+    timeout: 30s
+    expect_exit: 1
+`,
+		"child": `extends: [parent]
+project:
+  name: child
+architecture:
+  style: hexagonal
+standards:
+  backend: [Log with request IDs.]
+constraints: [Keep the API stable., No new dependencies.]
+quality_gates:
+  - name: lint
+    expect_exit: 0
+  - name: tests
+    command: go test ./...
+`,
+	})
+	ctx, err := resolveTemplateChain("child", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Project.Name != "child" || ctx.Project.Summary != "Parent summary." {
+		t.Errorf("project = %+v", ctx.Project)
+	}
+	if ctx.Architecture.Style != "hexagonal" || ctx.Architecture.Notes != "Parent notes." {
+		t.Errorf("architecture = %+v", ctx.Architecture)
+	}
+	if got := strings.Join(ctx.Standards["backend"], "|"); got != "Return typed errors.|Log with request IDs." {
+		t.Errorf("backend standards = %q", got)
+	}
+	if got := strings.Join(ctx.Constraints, "|"); got != "Keep the API stable.|No new dependencies." {
+		t.Errorf("constraints = %q", got)
+	}
+	if len(ctx.QualityGates) != 3 {
+		t.Fatalf("quality gates = %+v, want 3", ctx.QualityGates)
+	}
+	lint := ctx.QualityGates[1]
+	if lint.Key() != "lint" || lint.ExpectExit != 0 || lint.Command != "golangci-lint run" || lint.Timeout != 30*time.Second {
+		t.Errorf("lint gate = %+v, want expect_exit reset to 0 and the rest inherited", lint)
+	}
+	if ctx.QualityGates[2].Key() != "tests" {
+		t.Errorf("new gate should be appended, got %+v", ctx.QualityGates[2])
+	}
+}
+
+func TestMergeGateWithoutPresence(t *testing.T) {
+	// Gates built in Go fall back to overriding only non-zero fields.
+	parent := QualityGate{Name: "lint", Command: "lint", ExpectExit: 1}
+	got := mergeGate(parent, QualityGate{Name: "lint", Timeout: time.Minute})
+	if got.Command != "lint" || got.ExpectExit != 1 || got.Timeout != time.Minute {
+		t.Errorf("mergeGate = %+v", got)
+	}
+}
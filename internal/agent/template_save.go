@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateTemplateName rejects names that cannot be stored as .agent/templates/<name>.yaml
+// or that would be read as a composition.
+func ValidateTemplateName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("template name is empty")
+	case strings.ContainsAny(name, `/\`+templateLayerSep), strings.HasPrefix(name, "."):
+		return fmt.Errorf("invalid template name %q: use letters, digits, - or _", name)
+	}
+	return nil
+}
+
+// SaveTemplate writes .agent/context.yaml back as .agent/templates/<name>.yaml.
+// Generic drops the project name and summary so the template fits other repos.
+func SaveTemplate(name string, generic, force bool) (string, error) {
+	if err := ValidateTemplateName(name); err != nil {
+		return "", err
+	}
+	ctx, err := LoadContext()
+	if err != nil {
+		return "", err
+	}
+	ctx.Extends = nil
+	ctx.Project.Template = name
+	if generic {
+		ctx.Project.Name = ""
+		ctx.Project.Summary = ""
+	}
+	return writeRepoTemplate(name, ctx, force)
+}
+
+// ExportTemplate writes a template to a file outside .agent; resolved flattens the templates it extends
+// so the file does not depend on them.
+func ExportTemplate(name, path string, resolved, force bool) error {
+	data, err := TemplateYAML(name, resolved)
+	if err != nil {
+		return err
+	}
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ImportTemplate validates a template file and copies it into .agent/templates, named after the file unless name is set.
+// It returns the destination and warnings for parents in extends that this repo cannot resolve.
+func ImportTemplate(path, name string, force bool) (string, []string, error) {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := ValidateTemplateName(name); err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var ctx Context
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&ctx); err != nil {
+		return "", nil, fmt.Errorf("%s: not a ctx template: %w", path, err)
+	}
+	// The file is copied as is, keeping its comments.
+	dest, err := writeRepoTemplateData(name, data, force)
+	if err != nil {
+		return "", nil, err
+	}
+	var warnings []string
+	for _, parent := range ctx.Extends {
+		if _, err := resolveTemplateChain(strings.TrimSpace(parent), []string{name}); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return dest, warnings, nil
+}
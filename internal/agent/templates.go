@@ -3,7 +3,6 @@ package agent
 import (
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -41,11 +40,11 @@ var builtInTemplates = map[string]Context{
 			"Keep prompts token-cheap; expand context only when profile requests.",
 			"Maintain portable state inside the repo for agent switching and parallel work.",
 		},
-		QualityGates: []string{
+		QualityGates: stringGates(
 			"All tests pass and lint is clean.",
 			"No breaking API changes.",
 			"Prompt written to .agent/exports/current.prompt.md.",
-		},
+		),
 	},
 	"react-spring": {
 		Project: struct {
@@ -77,15 +76,19 @@ var builtInTemplates = map[string]Context{
 				"Document API contracts and align client/server versions.",
 			},
 		},
+		StandardsByIntent: map[string][]string{
+			"frontend": {"frontend"},
+			"backend":  {"backend"},
+		},
 		Constraints: []string{
 			"Keep prompts token-cheap; expand context only when profile requests.",
 			"Maintain portable state inside the repo for agent switching and parallel work.",
 		},
-		QualityGates: []string{
+		QualityGates: stringGates(
 			"All tests pass and lint is clean.",
 			"No breaking API changes.",
 			"Prompt written to .agent/exports/current.prompt.md.",
-		},
+		),
 	},
 }
 
@@ -100,17 +103,22 @@ func BuiltInTemplateNames() []string {
 }
 
 // ResolveTemplate loads a template by name, preferring repo templates, then built-ins, falling back to default.
+// Names joined with "+" (base+go-service) are resolved separately and layered left to right.
 func ResolveTemplate(templateName string) (Context, string, error) {
 	if templateName == "" {
 		templateName = "default"
 	}
-	if ctx, err := loadRepoTemplate(templateName); err == nil {
+	if strings.Contains(templateName, templateLayerSep) {
+		ctx, err := resolveTemplateLayers(strings.Split(templateName, templateLayerSep))
+		if err != nil {
+			return Context{}, "", err
+		}
 		return finalizeTemplateMetadata(ctx, templateName, templateName), templateName, nil
-	} else if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, os.ErrNotExist) {
-		return Context{}, "", err
 	}
-	if ctx, ok := builtInTemplate(templateName); ok {
+	if ctx, err := resolveTemplateChain(templateName, nil); err == nil {
 		return finalizeTemplateMetadata(ctx, templateName, templateName), templateName, nil
+	} else if !errors.Is(err, errTemplateNotFound) {
+		return Context{}, "", err
 	}
 	fallback, ok := builtInTemplate("default")
 	if !ok {
@@ -167,6 +175,19 @@ func InstallTemplate(name string, force bool) (string, error) {
 	if !ok {
 		return "", fmt.Errorf("built-in template %q not found", name)
 	}
+	return writeRepoTemplate(name, finalizeTemplateMetadata(ctx, name, name), force)
+}
+
+// writeRepoTemplate writes .agent/templates/<name>.yaml, refusing to overwrite without force.
+func writeRepoTemplate(name string, ctx Context, force bool) (string, error) {
+	data, err := yaml.Marshal(ctx)
+	if err != nil {
+		return "", err
+	}
+	return writeRepoTemplateData(name, data, force)
+}
+
+func writeRepoTemplateData(name string, data []byte, force bool) (string, error) {
 	if err := os.MkdirAll(AgentPath(templatesDir), 0o755); err != nil {
 		return "", err
 	}
@@ -178,11 +199,6 @@ func InstallTemplate(name string, force bool) (string, error) {
 			return "", err
 		}
 	}
-	ctx = finalizeTemplateMetadata(ctx, name, name)
-	data, err := yaml.Marshal(ctx)
-	if err != nil {
-		return "", err
-	}
 	if err := os.WriteFile(dest, data, 0o644); err != nil {
 		return "", err
 	}
@@ -213,13 +229,20 @@ func builtInTemplate(name string) (Context, bool) {
 func cloneContext(ctx Context) Context {
 	out := ctx
 	out.Constraints = append([]string(nil), ctx.Constraints...)
-	out.QualityGates = append([]string(nil), ctx.QualityGates...)
+	out.QualityGates = append([]QualityGate(nil), ctx.QualityGates...)
 	if ctx.Standards != nil {
 		out.Standards = make(map[string][]string, len(ctx.Standards))
 		for k, v := range ctx.Standards {
 			out.Standards[k] = append([]string(nil), v...)
 		}
 	}
+	out.Extends = append([]string(nil), ctx.Extends...)
+	if ctx.StandardsByIntent != nil {
+		out.StandardsByIntent = make(map[string][]string, len(ctx.StandardsByIntent))
+		for k, v := range ctx.StandardsByIntent {
+			out.StandardsByIntent[k] = append([]string(nil), v...)
+		}
+	}
 	return out
 }
 
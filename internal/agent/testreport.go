@@ -0,0 +1,465 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Test report formats for ctx evidence add --type.
+const (
+	ReportGoTestJSON = "go-test-json"
+	ReportJUnit      = "junit"
+	ReportTAP        = "tap"
+)
+
+// maxFailureMessage bounds the message stored per failure.
+const maxFailureMessage = 300
+
+// TestReport is the structured summary of a test report stored in the evidence index.
+type TestReport struct {
+	Format   string        `yaml:"format"`
+	Total    int           `yaml:"total"`
+	Passed   int           `yaml:"passed"`
+	Failed   int           `yaml:"failed"`
+	Skipped  int           `yaml:"skipped,omitempty"`
+	Failures []TestFailure `yaml:"failures,omitempty"`
+}
+
+// TestFailure is one failed test.
+type TestFailure struct {
+	Package string `yaml:"package,omitempty"`
+	Test    string `yaml:"test,omitempty"`
+	File    string `yaml:"file,omitempty"`
+	Line    int    `yaml:"line,omitempty"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// TestReportFormats lists the supported report formats.
+func TestReportFormats() []string {
+	return []string{ReportGoTestJSON, ReportJUnit, ReportTAP}
+}
+
+// ValidateTestReportFormat accepts "" (auto-detect) or a known format.
+func ValidateTestReportFormat(format string) error {
+	if format == "" || containsString(TestReportFormats(), format) {
+		return nil
+	}
+	return fmt.Errorf("unknown test report type %q (known: %s)", format, strings.Join(TestReportFormats(), ", "))
+}
+
+// fileLinePattern matches "path/file.ext:123" in test output.
+var fileLinePattern = regexp.MustCompile(`([A-Za-z0-9_./\\-]+\.[A-Za-z0-9]+):(\d+)`)
+
+// columnPattern matches the column compilers print after file:line.
+var columnPattern = regexp.MustCompile(`^:\d+`)
+
+var (
+	// ok 1 - description # SKIP reason
+	tapTestPattern = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(\w+)\b.*)?$`)
+	tapPlanPattern = regexp.MustCompile(`(?m)^1\.\.\d+`)
+)
+
+// detectTestReport guesses a report format from the name and content, or returns "".
+func detectTestReport(name string, data []byte) string {
+	if IsBinary(data) {
+		return ""
+	}
+	head := bytes.TrimSpace(data[:min(len(data), 4096)])
+	if bytes.HasPrefix(head, []byte("{")) && bytes.Contains(head, []byte(`"Action"`)) {
+		return ReportGoTestJSON
+	}
+	if (strings.HasSuffix(name, ".xml") || bytes.HasPrefix(head, []byte("<"))) && bytes.Contains(head, []byte("<testsuite")) {
+		return ReportJUnit
+	}
+	if bytes.HasPrefix(head, []byte("TAP version")) || strings.HasSuffix(name, ".tap") && tapPlanPattern.Match(head) {
+		return ReportTAP
+	}
+	return ""
+}
+
+// ParseTestReport parses a go test -json stream, a JUnit XML report or TAP output.
+func ParseTestReport(data []byte, format string) (*TestReport, error) {
+	switch format {
+	case ReportGoTestJSON:
+		return parseGoTestJSON(data)
+	case ReportJUnit:
+		return parseJUnit(data)
+	case ReportTAP:
+		return parseTAP(data)
+	}
+	return nil, ValidateTestReportFormat(format)
+}
+
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+func parseGoTestJSON(data []byte) (*TestReport, error) {
+	report := &TestReport{Format: ReportGoTestJSON}
+	module := goModulePath()
+	output := map[string]*strings.Builder{}
+	events := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			// go test -json interleaves plain build output; skip it.
+			continue
+		}
+		var ev goTestEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+		events++
+		key := ev.Package + "\x00" + ev.Test
+		switch ev.Action {
+		case "output":
+			b := output[key]
+			if b == nil {
+				b = &strings.Builder{}
+				output[key] = b
+			}
+			b.WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			if ev.Test == "" {
+				if ev.Action == "fail" && output[key] != nil && !packageHasFailures(report, ev.Package) {
+					// A package failing without a failed test is a build or setup failure.
+					report.Failures = append(report.Failures, goFailure(module, ev.Package, "", output[key].String()))
+				}
+				continue
+			}
+			report.Total++
+			switch ev.Action {
+			case "pass":
+				report.Passed++
+			case "skip":
+				report.Skipped++
+			case "fail":
+				report.Failed++
+				out := ""
+				if output[key] != nil {
+					out = output[key].String()
+				}
+				report.Failures = append(report.Failures, goFailure(module, ev.Package, ev.Test, out))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("no go test -json events found")
+	}
+	return report, nil
+}
+
+func packageHasFailures(r *TestReport, pkg string) bool {
+	for _, f := range r.Failures {
+		if f.Package == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// goFailure extracts file:line and the message from a failed test's output.
+func goFailure(module, pkg, test, output string) TestFailure {
+	f := TestFailure{Package: pkg, Test: test}
+	var msg []string
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") ||
+			trimmed == "FAIL" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "exit status") {
+			continue
+		}
+		if f.File == "" {
+			if m := fileLinePattern.FindStringSubmatchIndex(trimmed); m != nil && m[0] == 0 {
+				f.File = goRepoFile(module, pkg, trimmed[m[2]:m[3]])
+				f.Line, _ = strconv.Atoi(trimmed[m[4]:m[5]])
+				rest := trimmed[m[1]:]
+				if c := columnPattern.FindString(rest); c != "" {
+					rest = rest[len(c):]
+				}
+				trimmed = strings.TrimSpace(strings.TrimPrefix(rest, ":"))
+			}
+		}
+		msg = append(msg, trimmed)
+	}
+	f.Message = clipMessage(strings.Join(msg, " "))
+	return f
+}
+
+// goRepoFile maps a file named in go test output to a repo path using the package import path.
+func goRepoFile(module, pkg, file string) string {
+	if strings.Contains(file, "/") || module == "" {
+		return file
+	}
+	switch {
+	case pkg == module:
+		return file
+	case strings.HasPrefix(pkg, module+"/"):
+		return path.Join(strings.TrimPrefix(pkg, module+"/"), file)
+	}
+	return file
+}
+
+// goModulePath reads the module path from go.mod in the repo root, if any.
+func goModulePath() string {
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	File   string       `xml:"file,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func parseJUnit(data []byte) (*TestReport, error) {
+	var suites []junitSuite
+	var root struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse junit: %w", err)
+	}
+	switch root.XMLName.Local {
+	case "testsuites":
+		var all junitSuites
+		if err := xml.Unmarshal(data, &all); err != nil {
+			return nil, fmt.Errorf("parse junit: %w", err)
+		}
+		suites = all.Suites
+	case "testsuite":
+		var one junitSuite
+		if err := xml.Unmarshal(data, &one); err != nil {
+			return nil, fmt.Errorf("parse junit: %w", err)
+		}
+		suites = []junitSuite{one}
+	default:
+		return nil, fmt.Errorf("parse junit: unexpected root element <%s>", root.XMLName.Local)
+	}
+	report := &TestReport{Format: ReportJUnit}
+	for _, s := range suites {
+		addJUnitSuite(report, s)
+	}
+	return report, nil
+}
+
+func addJUnitSuite(report *TestReport, s junitSuite) {
+	for _, nested := range s.Suites {
+		addJUnitSuite(report, nested)
+	}
+	for _, c := range s.Cases {
+		report.Total++
+		problem := c.Failure
+		if problem == nil {
+			problem = c.Error
+		}
+		switch {
+		case problem != nil:
+			report.Failed++
+		case c.Skipped != nil:
+			report.Skipped++
+			continue
+		default:
+			report.Passed++
+			continue
+		}
+		pkg := c.Classname
+		if pkg == "" {
+			pkg = s.Name
+		}
+		f := TestFailure{Package: pkg, Test: c.Name, File: c.File, Line: c.Line}
+		if f.File == "" {
+			f.File = s.File
+		}
+		text := strings.TrimSpace(problem.Text)
+		if f.File == "" || f.Line == 0 {
+			if m := fileLinePattern.FindStringSubmatch(text); m != nil {
+				if f.File == "" || strings.HasSuffix(m[1], path.Base(f.File)) {
+					f.File = m[1]
+					f.Line, _ = strconv.Atoi(m[2])
+				}
+			}
+		}
+		msg := problem.Message
+		if msg == "" {
+			msg = text
+		}
+		if msg == "" {
+			msg = problem.Type
+		}
+		f.Message = clipMessage(strings.Join(strings.Fields(msg), " "))
+		report.Failures = append(report.Failures, f)
+	}
+}
+
+// parseTAP reads top-level TAP test lines; SKIP and TODO directives count as skipped, and a
+// failure's YAML diagnostics supply its message and location.
+func parseTAP(data []byte) (*TestReport, error) {
+	report := &TestReport{Format: ReportTAP}
+	lines := strings.Split(string(data), "\n")
+	tests := 0
+	for i := 0; i < len(lines); i++ {
+		m := tapTestPattern.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if m == nil {
+			continue
+		}
+		tests++
+		report.Total++
+		switch directive := strings.ToUpper(m[4]); {
+		case directive == "SKIP" || directive == "TODO":
+			report.Skipped++
+			continue
+		case m[1] == "":
+			report.Passed++
+			continue
+		}
+		report.Failed++
+		f := TestFailure{Test: m[3]}
+		if f.Test == "" {
+			f.Test = "test " + m[2]
+		}
+		// Diagnostics are the indented or "#" lines up to the next test line.
+		var diag []string
+		for i+1 < len(lines) && !tapTestPattern.MatchString(lines[i+1]) && !tapPlanPattern.MatchString(lines[i+1]) {
+			i++
+			if d := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), "#")); d != "" && d != "---" && d != "..." {
+				diag = append(diag, d)
+			}
+		}
+		for _, d := range diag {
+			key, value, ok := strings.Cut(d, ":")
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch {
+			case ok && key == "message" && f.Message == "":
+				f.Message = clipMessage(value)
+			case ok && (key == "at" || key == "file") && f.File == "":
+				if m := fileLinePattern.FindStringSubmatch(value); m != nil {
+					f.File = m[1]
+					f.Line, _ = strconv.Atoi(m[2])
+				} else {
+					f.File = value
+				}
+			case ok && key == "line" && f.Line == 0:
+				f.Line, _ = strconv.Atoi(value)
+			}
+		}
+		if f.Message == "" && len(diag) > 0 {
+			f.Message = clipMessage(strings.Join(diag, " "))
+		}
+		report.Failures = append(report.Failures, f)
+	}
+	if tests == 0 {
+		return nil, fmt.Errorf("no TAP test lines found")
+	}
+	return report, nil
+}
+
+func clipMessage(msg string) string {
+	if len(msg) <= maxFailureMessage {
+		return msg
+	}
+	return strings.TrimSpace(msg[:maxFailureMessage]) + "..."
+}
+
+// String renders a failure as one compact line for prompts.
+func (f TestFailure) String() string {
+	name := f.Test
+	if f.Package != "" && f.Test != "" {
+		name = f.Package + "." + f.Test
+	} else if name == "" {
+		name = f.Package + " (package)"
+	}
+	if loc := f.Location(); loc != "" {
+		name += " at " + loc
+	}
+	if f.Message != "" {
+		name += ": " + f.Message
+	}
+	return name
+}
+
+// Location returns file:line, the file alone, or "".
+func (f TestFailure) Location() string {
+	if f.File == "" {
+		return ""
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// SetTestReport parses stored evidence as a test report of the given format and records it in the index.
+func SetTestReport(rel, format string) (*TestReport, error) {
+	data, err := ReadEvidence(rel)
+	if err != nil {
+		return nil, err
+	}
+	report, err := ParseTestReport(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rel, err)
+	}
+	idx, err := LoadEvidenceIndex()
+	if err != nil {
+		return nil, err
+	}
+	entry := idx.Find(rel)
+	if entry == nil {
+		return nil, fmt.Errorf("%s is not in the evidence index", rel)
+	}
+	entry.Tests = report
+	return report, SaveEvidenceIndex(idx)
+}
+
+// failingTests collects failures from the test reports among a work item's evidence.
+func failingTests(evidence []string, idx EvidenceIndex) []TestFailure {
+	var out []TestFailure
+	for _, rel := range evidence {
+		if entry := idx.Find(rel); entry != nil && entry.Tests != nil {
+			out = append(out, entry.Tests.Failures...)
+		}
+	}
+	return out
+}
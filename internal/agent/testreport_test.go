@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func goEvents(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestParseTestReport(t *testing.T) {
+	inTempRepo(t)
+	if err := os.WriteFile("go.mod", []byte("module example.com/up\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name, format, input string
+		want                *TestReport
+		wantErr             string
+	}{
+		{
+			name:   "go test json",
+			format: ReportGoTestJSON,
+			input: goEvents(
+				`{"Action":"run","Package":"example.com/up/api","Test":"TestUploadLimit"}`,
+				`{"Action":"output","Package":"example.com/up/api","Test":"TestUploadLimit","Output":"=== RUN   TestUploadLimit\n"}`,
+				`{"Action":"output","Package":"example.com/up/api","Test":"TestUploadLimit","Output":"    upload_test.go:42: got 200, want 413\n"}`,
+				`{"Action":"output","Package":"example.com/up/api","Test":"TestUploadLimit","Output":"--- FAIL: TestUploadLimit (0.00s)\n"}`,
+				`{"Action":"fail","Package":"example.com/up/api","Test":"TestUploadLimit"}`,
+				`{"Action":"pass","Package":"example.com/up/api","Test":"TestUploadZero"}`,
+				`{"Action":"skip","Package":"example.com/up/api","Test":"TestUploadSlow"}`,
+				`{"Action":"fail","Package":"example.com/up/api"}`,
+			),
+			want: &TestReport{Format: ReportGoTestJSON, Total: 3, Passed: 1, Failed: 1, Skipped: 1, Failures: []TestFailure{
+				{Package: "example.com/up/api", Test: "TestUploadLimit", File: "api/upload_test.go", Line: 42, Message: "got 200, want 413"},
+			}},
+		},
+		{
+			name:   "go test json interleaved packages",
+			format: ReportGoTestJSON,
+			input: goEvents(
+				`{"Action":"output","Package":"example.com/up/api","Test":"TestA","Output":"    a_test.go:10: api failed\n"}`,
+				`{"Action":"output","Package":"example.com/up/store","Test":"TestA","Output":"    b_test.go:20: store failed\n"}`,
+				`{"Action":"fail","Package":"example.com/up/store","Test":"TestA"}`,
+				`{"Action":"fail","Package":"example.com/up/api","Test":"TestA"}`,
+			),
+			want: &TestReport{Format: ReportGoTestJSON, Total: 2, Failed: 2, Failures: []TestFailure{
+				{Package: "example.com/up/store", Test: "TestA", File: "store/b_test.go", Line: 20, Message: "store failed"},
+				{Package: "example.com/up/api", Test: "TestA", File: "api/a_test.go", Line: 10, Message: "api failed"},
+			}},
+		},
+		{
+			name:   "go test json build failure and plain lines",
+			format: ReportGoTestJSON,
+			input: goEvents(
+				`# example.com/up/api`,
+				`{"Action":"output","Package":"example.com/up/api","Output":"api/upload.go:12:2: undefined: sizeLimit\n"}`,
+				`{"Action":"output","Package":"example.com/up/api","Output":"FAIL\texample.com/up/api [build failed]\n"}`,
+				`{"Action":"fail","Package":"example.com/up/api"`,
+				`{"Action":"fail","Package":"example.com/up/api"}`,
+			),
+			want: &TestReport{Format: ReportGoTestJSON, Failures: []TestFailure{
+				{Package: "example.com/up/api", File: "api/upload.go", Line: 12, Message: "undefined: sizeLimit"},
+			}},
+		},
+		{
+			name:    "go test json without events",
+			format:  ReportGoTestJSON,
+			input:   "ok  \texample.com/up/api\t0.01s\n{not json}\n",
+			wantErr: "no go test -json events found",
+		},
+		{
+			name:   "junit nested suites",
+			format: ReportJUnit,
+			input: `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="api" file="api/upload_test.py">
+    <testcase classname="api.UploadTest" name="test_limit" line="42">
+      <failure message="expected 413">Traceback</failure>
+    </testcase>
+    <testcase classname="api.UploadTest" name="test_zero"/>
+    <testsuite name="api.slow">
+      <testcase classname="api.SlowTest" name="test_big"><skipped/></testcase>
+      <testcase name="test_crash"><error type="RuntimeError">store/put.py:7: boom</error></testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`,
+			want: &TestReport{Format: ReportJUnit, Total: 4, Passed: 1, Failed: 2, Skipped: 1, Failures: []TestFailure{
+				{Package: "api.slow", Test: "test_crash", File: "store/put.py", Line: 7, Message: "store/put.py:7: boom"},
+				{Package: "api.UploadTest", Test: "test_limit", File: "api/upload_test.py", Line: 42, Message: "expected 413"},
+			}},
+		},
+		{
+			name:   "junit single suite",
+			format: ReportJUnit,
+			input:  `<testsuite name="web"><testcase classname="web" name="renders" file="web/app.test.js"><failure>web/app.test.js:9 expected true</failure></testcase></testsuite>`,
+			want: &TestReport{Format: ReportJUnit, Total: 1, Failed: 1, Failures: []TestFailure{
+				{Package: "web", Test: "renders", File: "web/app.test.js", Line: 9, Message: "web/app.test.js:9 expected true"},
+			}},
+		},
+		{
+			name:    "junit malformed",
+			format:  ReportJUnit,
+			input:   `<testsuite name="web"><testcase name="x">`,
+			wantErr: "parse junit",
+		},
+		{
+			name:    "junit wrong root",
+			format:  ReportJUnit,
+			input:   `<report/>`,
+			wantErr: "unexpected root element <report>",
+		},
+		{
+			name:   "tap",
+			format: ReportTAP,
+			input: `TAP version 13
+1..5
+ok 1 - parses sizes
+not ok 2 - rejects large uploads
+  ---
+  message: "expected 413, got 200"
+  at: api/upload.test.js:42:7
+  ...
+ok 3 - slow path # SKIP no fixtures
+not ok 4 - retries # TODO flaky
+# Subtest: nested
+    not ok 1 - nested failure is summarized by its parent
+    1..1
+not ok 5
+# store/put.js:7 boom
+`,
+			want: &TestReport{Format: ReportTAP, Total: 5, Passed: 1, Failed: 2, Skipped: 2, Failures: []TestFailure{
+				{Test: "rejects large uploads", File: "api/upload.test.js", Line: 42, Message: "expected 413, got 200"},
+				{Test: "test 5", Message: "store/put.js:7 boom"},
+			}},
+		},
+		{
+			name:    "tap without tests",
+			format:  ReportTAP,
+			input:   "TAP version 13\n1..0\n",
+			wantErr: "no TAP test lines found",
+		},
+		{
+			name:    "unknown format",
+			format:  "xunit",
+			wantErr: `unknown test report type "xunit"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseTestReport([]byte(tc.input), tc.format)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("report:\n got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectTestReport(t *testing.T) {
+	cases := []struct {
+		name, data, want string
+	}{
+		{"test.json", `{"Time":"x","Action":"run","Package":"p"}`, ReportGoTestJSON},
+		{"report.xml", `<?xml version="1.0"?><testsuites><testsuite/></testsuites>`, ReportJUnit},
+		{"out.log", "TAP version 13\n1..1\nok 1\n", ReportTAP},
+		{"out.tap", "1..1\nok 1\n", ReportTAP},
+		{"out.log", "1..1\nok 1\n", ""},
+		{"build.log", "ok  \texample.com/up\t0.1s\n", ""},
+	}
+	for _, tc := range cases {
+		if got := detectTestReport(tc.name, []byte(tc.data)); got != tc.want {
+			t.Errorf("detectTestReport(%q, %q) = %q, want %q", tc.name, tc.data, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package agent
+
+import (
+	"bufio"
+	"compress/gzip"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	tokenizersDir = "tokenizers"
+	// cl100kName is the built-in exact tokenizer, available when its rank file is embedded.
+	cl100kName = "cl100k_base"
+)
+
+// vocabFS holds gzipped tiktoken rank files compiled into the binary as vocab/<name>.tiktoken.gz.
+//
+//go:embed vocab
+var vocabFS embed.FS
+
+var (
+	embeddedMu   sync.Mutex
+	embeddedBPEs = map[string]*bpeTokenizer{}
+)
+
+// Tokenizer counts tokens offline for prompt budgets and stats.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// cl100kPieces mirrors the cl100k_base pre-tokenizer. Go's regexp has no lookahead, so the
+// trailing-whitespace rule (`\s+(?!\S)`) is applied by splitPieces instead.
+var cl100kPieces = regexp.MustCompile(`^(?:(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+)`)
+
+// EstimateTokens approximates the token count of text offline (about four characters per token).
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
+
+// DefaultTokenizer is used when a profile does not name one: cl100k_base when its
+// vocabulary is embedded, otherwise the approx estimate.
+func DefaultTokenizer() string {
+	if hasEmbeddedVocab(cl100kName) {
+		return cl100kName
+	}
+	return "approx"
+}
+
+// BuiltInTokenizerNames returns the tokenizers compiled into ctx.
+func BuiltInTokenizerNames() []string {
+	names := []string{"chars4", "approx"}
+	paths, _ := fs.Glob(vocabFS, "vocab/*.tiktoken.gz")
+	for _, p := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(p), ".tiktoken.gz"))
+	}
+	return names
+}
+
+// LoadTokenizer resolves a tokenizer by name: built-ins first, then .agent/tokenizers/<name>.tiktoken.
+func LoadTokenizer(name string) (Tokenizer, error) {
+	if name == "" {
+		name = DefaultTokenizer()
+	}
+	switch name {
+	case "chars4":
+		return charsTokenizer{}, nil
+	case "approx", "cl100k-approx":
+		// cl100k-approx is the earlier name of the estimate.
+		return approxTokenizer{}, nil
+	}
+	if hasEmbeddedVocab(name) {
+		return loadEmbeddedBPE(name)
+	}
+	path := AgentPath(tokenizersDir, name+".tiktoken")
+	tok, err := loadBPETokenizer(name, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("tokenizer %q not found (built-ins: %s; or add %s)", name, strings.Join(BuiltInTokenizerNames(), ", "), path)
+		}
+		return nil, fmt.Errorf("tokenizer %q: %w", name, err)
+	}
+	return tok, nil
+}
+
+// ListRepoTokenizers returns vocabulary names under .agent/tokenizers.
+func ListRepoTokenizers() ([]string, error) {
+	entries, err := os.ReadDir(AgentPath(tokenizersDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tiktoken" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), ".tiktoken"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// charsTokenizer is the legacy four-characters-per-token estimate.
+type charsTokenizer struct{}
+
+func (charsTokenizer) Name() string { return "chars4" }
+
+func (charsTokenizer) Count(text string) int { return EstimateTokens(text) }
+
+// approxTokenizer splits text like cl100k_base and prices each piece without a vocabulary.
+type approxTokenizer struct{}
+
+func (approxTokenizer) Name() string { return "approx" }
+
+func (approxTokenizer) Count(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text) {
+		total += approxPieceTokens(piece)
+	}
+	return total
+}
+
+func approxPieceTokens(piece string) int {
+	runes := []rune(piece)
+	if len(runes) == 0 {
+		return 0
+	}
+	switch {
+	case strings.TrimSpace(piece) == "":
+		// Runs of spaces and newlines are merged aggressively by cl100k.
+		return (len(runes) + 15) / 16
+	case unicode.IsDigit(runes[0]):
+		return 1
+	}
+	letters := strings.TrimLeftFunc(piece, func(r rune) bool { return !unicode.IsLetter(r) })
+	if letters == "" {
+		// Punctuation and operator runs.
+		return (len(strings.TrimSpace(piece)) + 1) / 2
+	}
+	prefix := 0
+	if lead := len(piece) - len(letters); lead > 0 && piece[:lead] != " " {
+		prefix = 1
+	}
+	total := prefix
+	for _, word := range camelWords(letters) {
+		if wide := wideRunes(word); wide > 0 {
+			// CJK text is mostly one token per character.
+			total += wide
+			continue
+		}
+		n := utf8.RuneCountInString(word)
+		if n <= 8 {
+			total++
+			continue
+		}
+		total += (n + 5) / 6
+	}
+	return total
+}
+
+func wideRunes(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x3000 {
+			n++
+		}
+	}
+	return n
+}
+
+// camelWords splits identifiers such as ExecuteTemplate or HTTPServer into their humps.
+func camelWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := unicode.IsLower(prev) && unicode.IsUpper(cur)
+		if !boundary && i+1 < len(runes) {
+			boundary = unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(runes[i+1])
+		}
+		if boundary {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// splitPieces applies the cl100k pre-tokenizer, leaving the last space of a run for the next word.
+func splitPieces(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		loc := cl100kPieces.FindStringIndex(text[i:])
+		if loc == nil || loc[1] == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			pieces = append(pieces, text[i:i+size])
+			i += size
+			continue
+		}
+		piece := text[i : i+loc[1]]
+		if rest := text[i+loc[1]:]; rest != "" && strings.TrimSpace(piece) == "" && !strings.ContainsAny(piece, "\r\n") {
+			if _, size := utf8.DecodeLastRuneInString(piece); len(piece) > size {
+				piece = piece[:len(piece)-size]
+			}
+		}
+		pieces = append(pieces, piece)
+		i += len(piece)
+	}
+	return pieces
+}
+
+// bpeTokenizer performs byte-level BPE with a tiktoken-format vocabulary.
+type bpeTokenizer struct {
+	name  string
+	ranks map[string]int
+	cache map[string]int
+}
+
+func (t *bpeTokenizer) Name() string { return t.name }
+
+func (t *bpeTokenizer) Count(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text) {
+		if n, ok := t.cache[piece]; ok {
+			total += n
+			continue
+		}
+		n := t.encodeLen([]byte(piece))
+		t.cache[piece] = n
+		total += n
+	}
+	return total
+}
+
+// encodeLen repeatedly merges the adjacent pair with the lowest rank and returns the part count.
+func (t *bpeTokenizer) encodeLen(piece []byte) int {
+	if _, ok := t.ranks[string(piece)]; ok {
+		return 1
+	}
+	// bounds holds the start offset of every part plus the end of the piece.
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := t.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+func embeddedVocabPath(name string) string {
+	return "vocab/" + name + ".tiktoken.gz"
+}
+
+func hasEmbeddedVocab(name string) bool {
+	_, err := fs.Stat(vocabFS, embeddedVocabPath(name))
+	return err == nil
+}
+
+// loadEmbeddedBPE decodes an embedded vocabulary once and shares it afterwards.
+func loadEmbeddedBPE(name string) (*bpeTokenizer, error) {
+	embeddedMu.Lock()
+	defer embeddedMu.Unlock()
+	if tok, ok := embeddedBPEs[name]; ok {
+		return tok, nil
+	}
+	path := embeddedVocabPath(name)
+	f, err := vocabFS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	tok, err := parseBPERanks(name, path, zr)
+	if err != nil {
+		return nil, err
+	}
+	embeddedBPEs[name] = tok
+	return tok, nil
+}
+
+// loadBPETokenizer reads a tiktoken-format vocabulary from disk.
+func loadBPETokenizer(name, path string) (*bpeTokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBPERanks(name, path, f)
+}
+
+// parseBPERanks reads "<base64 token> <rank>" lines as written by tiktoken.
+func parseBPERanks(name, path string, r io.Reader) (*bpeTokenizer, error) {
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<base64 token> <rank>\"", path, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("%s: empty vocabulary", path)
+	}
+	return &bpeTokenizer{name: name, ranks: ranks, cache: map[string]int{}}, nil
+}
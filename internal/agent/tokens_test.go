@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Counts from tiktoken's cl100k_base encoding.
+var cl100kCounts = []struct {
+	name, text string
+	want       int
+}{
+	{"prose", "hello world", 2},
+	{"prose punctuation", "tiktoken is great!", 6},
+	{"long word", "antidisestablishmentarianism", 6},
+	{"non-latin", "お誕生日おめでとう", 9},
+	{"arithmetic", "2 + 2 = 4", 7},
+	{"go func", "func main() {}", 4},
+}
+
+func TestCl100kKnownCounts(t *testing.T) {
+	if !hasEmbeddedVocab(cl100kName) {
+		t.Skip("vocab/cl100k_base.tiktoken.gz is not embedded")
+	}
+	tok, err := LoadTokenizer(cl100kName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cl100kCounts {
+		if got := tok.Count(tc.text); got != tc.want {
+			t.Errorf("%s: Count(%q) = %d, want %d", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestApproxNearCl100kCounts(t *testing.T) {
+	tok, err := LoadTokenizer("approx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cl100kCounts {
+		if got := tok.Count(tc.text); got < tc.want/2 || got > tc.want*2 {
+			t.Errorf("%s: approx Count(%q) = %d, want within 2x of %d", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLoadTokenizerNames(t *testing.T) {
+	for name, want := range map[string]string{"approx": "approx", "cl100k-approx": "approx", "chars4": "chars4", "": DefaultTokenizer()} {
+		tok, err := LoadTokenizer(name)
+		if err != nil {
+			t.Fatalf("LoadTokenizer(%q): %v", name, err)
+		}
+		if tok.Name() != want {
+			t.Errorf("LoadTokenizer(%q).Name() = %q, want %q", name, tok.Name(), want)
+		}
+	}
+	inTempRepo(t)
+	if _, err := LoadTokenizer("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("LoadTokenizer(missing) error = %v, want not found", err)
+	}
+}
+
+// writeVocab writes ranks in tiktoken format, ranked in the order given.
+func writeVocab(t *testing.T, tokens ...string) string {
+	t.Helper()
+	var b strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "tiny.tiktoken")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPEMergeLoop(t *testing.T) {
+	path := writeVocab(t, "a", "b", "c", "d", " ", "bc", "ab", "cd", "abab", "xyz")
+	tok, err := loadBPETokenizer("tiny", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		piece string
+		want  int
+	}{
+		{"xyz", 1},   // whole piece is a token
+		{"abc", 2},   // bc (rank 5) merges before ab (6): a|bc
+		{"abcd", 3},  // a|bc|d; merging cd first would give ab|cd
+		{"ababa", 2}, // ab|ab|a, then abab|a
+		{"cab", 2},   // c|ab
+		{"zz", 2},    // unknown bytes stay single parts
+		{"", 0},
+	}
+	for _, tc := range cases {
+		if got := tok.encodeLen([]byte(tc.piece)); got != tc.want {
+			t.Errorf("encodeLen(%q) = %d, want %d", tc.piece, got, tc.want)
+		}
+	}
+	// Count pre-tokenizes first, so the space starts its own piece before "ab".
+	if got := tok.Count("abcd abab"); got != 3+2 {
+		t.Errorf("Count = %d, want 5", got)
+	}
+}
+
+func TestLoadBPETokenizerErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"empty":  "",
+		"fields": "YQ==\n",
+		"base64": "!!! 0\n",
+		"rank":   "YQ== x\n",
+	} {
+		path := filepath.Join(dir, name+".tiktoken")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadBPETokenizer(name, path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}